@@ -1,31 +1,115 @@
 package config
 
 import (
+	"flag"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"sonus-metrics-exporter/lib"
+
 	cfg "github.com/infinityworks/go-common/config"
 )
 
 // Config struct holds all the runtime configuration for the application
 type Config struct {
 	*cfg.BaseConfig
-	APIURLs            []string
-	APIUser            string
-	APIPass            string
-	APIAddressContexts []string
-	APITimeout         time.Duration
+	ConfigFile     string
+	CollectorsFile string
+	Targets        []Target
+
+	// AllowAdhocTargets lets /probe scrape SBC addresses which are not configured targets
+	AllowAdhocTargets bool
 }
 
-// Init populates the Config struct based on environmental runtime configuration
+// Init populates the Config struct based on environmental runtime configuration.
+// Targets are read from the file given by --config.file or CONFIG_FILE when set,
+// otherwise a single target named "default" is built from the API_* variables.
 func Init() Config {
 
 	c := cfg.Init()
+
+	configFile := flag.String("config.file", cfg.GetEnv("CONFIG_FILE", ""), "Path to a YAML file defining the SBC targets to scrape.")
+	collectorsFile := flag.String("collectors.file", cfg.GetEnv("COLLECTORS_FILE", ""), "Path to a YAML file defining custom collectors.")
+	allowAdhocTargets := flag.Bool("probe.allow-adhoc-targets", cfg.GetEnv("PROBE_ALLOW_ADHOC_TARGETS", "false") == "true", "Allow /probe to scrape SBC addresses which are not configured targets, without credentials.")
+	flag.Parse()
+
+	appConfig := Config{
+		BaseConfig:     &c,
+		ConfigFile:     *configFile,
+		CollectorsFile: *collectorsFile,
+
+		AllowAdhocTargets: *allowAdhocTargets,
+	}
+
+	appConfig, err := appConfig.Reload()
+	if err != nil {
+		panic("Unable to load configuration: " + err.Error())
+	}
+
+	return appConfig
+}
+
+// Reload returns a copy of the Config with its targets re-read from the configuration
+// file, or from the environment when no file is in use.
+func (c Config) Reload() (Config, error) {
+	if c.ConfigFile != "" {
+		targets, err := LoadFile(c.ConfigFile)
+		if err != nil {
+			return c, fmt.Errorf("unable to load %s: %v", c.ConfigFile, err)
+		}
+		c.Targets = targets
+	} else {
+		t, err := envTarget()
+		if err != nil {
+			return c, err
+		}
+		c.Targets = []Target{t}
+	}
+
+	return c, nil
+}
+
+// DefaultTarget returns the target served on the metrics path, which is the first one configured
+func (c Config) DefaultTarget() Target {
+	return c.Targets[0]
+}
+
+// Target returns the configured target with the given name
+func (c Config) Target(name string) (Target, bool) {
+	for _, t := range c.Targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Target{}, false
+}
+
+// envTarget builds the single target described by the API_* environment variables
+func envTarget() (Target, error) {
 	rawURLs := cfg.GetEnv("API_URLS", "https://172.16.7.2/api")
 	rawACs := cfg.GetEnv("API_ADDRESSCONTEXTS", "default")
 	rawTimeout := cfg.GetEnv("API_TIMEOUT", "10")
+	rawConcurrency := cfg.GetEnv("API_CONCURRENCY", "1")
+	rawPollInterval := cfg.GetEnv("API_POLL_INTERVAL", "0")
+	rawTopologyCacheTTL := cfg.GetEnv("API_TOPOLOGY_CACHE_TTL", "0")
+	rawCollectors := os.Getenv("API_COLLECTORS")
+	rawInsecure := cfg.GetEnv("API_TLS_INSECURE_SKIP_VERIFY", "false")
+	rawAuthMode := cfg.GetEnv("API_AUTH_MODE", AuthModeBasic)
+	rawSessionTTL := cfg.GetEnv("API_SESSION_TTL", "0")
+	rawDisabledCollectors := os.Getenv("API_DISABLED_COLLECTORS")
+	rawCollectorTimeouts := os.Getenv("API_COLLECTOR_TIMEOUTS")
+	rawSeriesLimit := cfg.GetEnv("API_SERIES_LIMIT", "0")
+	rawCollectorSeriesLimits := os.Getenv("API_COLLECTOR_SERIES_LIMITS")
+	rawDiscovery := cfg.GetEnv("API_ADDRESSCONTEXT_DISCOVERY", "false")
+	rawCompatRetransmissions := cfg.GetEnv("API_COMPAT_SIP_RETRANSMISSION_METHODS", "false")
+	rawRetryAttempts := cfg.GetEnv("API_RETRY_ATTEMPTS", "3")
+	rawRetryBackoff := cfg.GetEnv("API_RETRY_BACKOFF_MS", "200")
+	rawRetryMaxBackoff := cfg.GetEnv("API_RETRY_MAX_BACKOFF_MS", "2000")
+	rawBreakerThreshold := cfg.GetEnv("API_CIRCUIT_BREAKER_THRESHOLD", "5")
+	rawBreakerOpenDuration := cfg.GetEnv("API_CIRCUIT_BREAKER_OPEN_DURATION", "30")
 
 	user := os.Getenv("API_USER")
 	pass := os.Getenv("API_PASSWORD")
@@ -37,17 +121,170 @@ func Init() Config {
 	timeout := time.Duration(intTimeout) * time.Second
 
 	if err != nil {
-		panic("Unable to parse API_TIMEOUT as integer.")
+		return Target{}, fmt.Errorf("unable to parse API_TIMEOUT as integer")
 	}
 
-	appConfig := Config{
-		&c,
-		urls,
-		user,
-		pass,
-		addressContexts,
-		timeout,
+	concurrency, err := strconv.Atoi(rawConcurrency)
+	if err != nil || concurrency < 1 {
+		return Target{}, fmt.Errorf("API_CONCURRENCY must be an integer of at least 1")
 	}
 
-	return appConfig
+	intPollInterval, err := strconv.ParseInt(rawPollInterval, 0, 0)
+	if err != nil || intPollInterval < 0 {
+		return Target{}, fmt.Errorf("API_POLL_INTERVAL must be a non-negative integer")
+	}
+
+	intTopologyCacheTTL, err := strconv.ParseInt(rawTopologyCacheTTL, 0, 0)
+	if err != nil || intTopologyCacheTTL < 0 {
+		return Target{}, fmt.Errorf("API_TOPOLOGY_CACHE_TTL must be a non-negative integer")
+	}
+
+	intSessionTTL, err := strconv.ParseInt(rawSessionTTL, 0, 0)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_SESSION_TTL as integer")
+	}
+
+	retryAttempts, err := strconv.Atoi(rawRetryAttempts)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_RETRY_ATTEMPTS as integer")
+	}
+
+	intRetryBackoff, err := strconv.ParseInt(rawRetryBackoff, 0, 0)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_RETRY_BACKOFF_MS as integer")
+	}
+
+	intRetryMaxBackoff, err := strconv.ParseInt(rawRetryMaxBackoff, 0, 0)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_RETRY_MAX_BACKOFF_MS as integer")
+	}
+
+	breakerThreshold, err := strconv.Atoi(rawBreakerThreshold)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_CIRCUIT_BREAKER_THRESHOLD as integer")
+	}
+
+	intBreakerOpenDuration, err := strconv.ParseInt(rawBreakerOpenDuration, 0, 0)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_CIRCUIT_BREAKER_OPEN_DURATION as integer")
+	}
+
+	collectorTimeouts := make(map[string]time.Duration)
+	for _, ct := range strings.Fields(rawCollectorTimeouts) {
+		name, rawSeconds, ok := strings.Cut(ct, "=")
+		seconds, err := strconv.ParseInt(rawSeconds, 0, 0)
+		if !ok || err != nil || seconds <= 0 {
+			return Target{}, fmt.Errorf("API_COLLECTOR_TIMEOUTS entries must be given as collector=seconds")
+		}
+		collectorTimeouts[name] = time.Duration(seconds) * time.Second
+	}
+
+	seriesLimit, err := strconv.Atoi(rawSeriesLimit)
+	if err != nil || seriesLimit < 0 {
+		return Target{}, fmt.Errorf("API_SERIES_LIMIT must be a non-negative integer")
+	}
+
+	collectorSeriesLimits := make(map[string]int)
+	for _, sl := range strings.Fields(rawCollectorSeriesLimits) {
+		name, rawLimit, ok := strings.Cut(sl, "=")
+		limit, err := strconv.Atoi(rawLimit)
+		if !ok || err != nil || limit < 0 {
+			return Target{}, fmt.Errorf("API_COLLECTOR_SERIES_LIMITS entries must be given as collector=series")
+		}
+		collectorSeriesLimits[name] = limit
+	}
+
+	discovery, err := strconv.ParseBool(rawDiscovery)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_ADDRESSCONTEXT_DISCOVERY as boolean")
+	}
+
+	compatRetransmissions, err := strconv.ParseBool(rawCompatRetransmissions)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_COMPAT_SIP_RETRANSMISSION_METHODS as boolean")
+	}
+
+	insecure, err := strconv.ParseBool(rawInsecure)
+	if err != nil {
+		return Target{}, fmt.Errorf("unable to parse API_TLS_INSECURE_SKIP_VERIFY as boolean")
+	}
+
+	t := newTarget()
+	t.Name = "default"
+	t.APIURLs = urls
+	t.APIUser = user
+	t.APIPass = Secret(pass)
+	t.APIPassFile = os.Getenv("API_PASSWORD_FILE")
+	t.APIAddressContexts = addressContexts
+	t.Discovery = DiscoveryConfig{
+		Enabled:    discovery,
+		NameFilter: envNameFilter("API_ADDRESSCONTEXT"),
+	}
+	t.Filters = Filters{
+		Zones:           envNameFilter("API_FILTER_ZONE"),
+		TrunkGroups:     envNameFilter("API_FILTER_TRUNKGROUP"),
+		IPInterfaces:    envNameFilter("API_FILTER_IPINTERFACE"),
+		SipArsEndpoints: envNameFilter("API_FILTER_SIPARS_ENDPOINT"),
+	}
+	t.APITimeout = timeout
+	t.Concurrency = concurrency
+	t.PollInterval = time.Duration(intPollInterval) * time.Second
+	t.TopologyCacheTTL = time.Duration(intTopologyCacheTTL) * time.Second
+	t.Collectors = strings.Fields(rawCollectors)
+	t.DisabledCollectors = strings.Fields(rawDisabledCollectors)
+	t.CollectorTimeouts = collectorTimeouts
+	t.SeriesLimit = seriesLimit
+	t.CollectorSeriesLimits = collectorSeriesLimits
+	t.Compatibility = lib.Compatibility{SIPRetransmissionMethods: compatRetransmissions}
+	t.TLS = TLSConfig{
+		CAFile:             os.Getenv("API_TLS_CA_FILE"),
+		CertFile:           os.Getenv("API_TLS_CERT_FILE"),
+		KeyFile:            os.Getenv("API_TLS_KEY_FILE"),
+		ServerName:         os.Getenv("API_TLS_SERVER_NAME"),
+		MinVersion:         os.Getenv("API_TLS_MIN_VERSION"),
+		InsecureSkipVerify: insecure,
+	}
+	t.Auth.Mode = rawAuthMode
+	t.Auth.SessionTTL = time.Duration(intSessionTTL) * time.Second
+	t.Retry = RetryConfig{
+		Attempts:   retryAttempts,
+		Backoff:    time.Duration(intRetryBackoff) * time.Millisecond,
+		MaxBackoff: time.Duration(intRetryMaxBackoff) * time.Millisecond,
+	}
+	t.CircuitBreaker = BreakerConfig{
+		FailureThreshold: breakerThreshold,
+		OpenDuration:     time.Duration(intBreakerOpenDuration) * time.Second,
+	}
+
+	if err := t.Auth.validate(); err != nil {
+		return Target{}, err
+	}
+	if err := t.Retry.validate(); err != nil {
+		return Target{}, err
+	}
+	if err := t.Discovery.validate(); err != nil {
+		return Target{}, err
+	}
+	if err := t.CircuitBreaker.validate(); err != nil {
+		return Target{}, err
+	}
+	if err := t.Filters.validate(); err != nil {
+		return Target{}, err
+	}
+	if err := t.validatePassword(); err != nil {
+		return Target{}, err
+	}
+	if _, err := t.TLS.Build(); err != nil {
+		return Target{}, err
+	}
+
+	return t, nil
+}
+
+// envNameFilter reads a NameFilter from the _INCLUDE and _EXCLUDE environment variables with the given prefix
+func envNameFilter(prefix string) NameFilter {
+	return NameFilter{
+		Include: os.Getenv(prefix + "_INCLUDE"),
+		Exclude: os.Getenv(prefix + "_EXCLUDE"),
+	}
 }
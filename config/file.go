@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the layout of the YAML configuration file
+type fileConfig struct {
+	Targets []Target `yaml:"targets"`
+}
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LoadFile reads and validates the targets defined in a YAML configuration file
+func LoadFile(path string) ([]Target, error) {
+	var fc fileConfig
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&fc); err != nil {
+		return nil, fmt.Errorf("unable to parse YAML: %v", err)
+	}
+
+	if err := validateTargets(fc.Targets); err != nil {
+		return nil, err
+	}
+
+	return fc.Targets, nil
+}
+
+func (a AuthConfig) validate() error {
+	if a.Mode != AuthModeBasic && a.Mode != AuthModeSession {
+		return fmt.Errorf("unknown auth mode %q", a.Mode)
+	}
+	if a.SessionTTL < 0 {
+		return fmt.Errorf("negative session_ttl")
+	}
+	return nil
+}
+
+func (d DiscoveryConfig) validate() error {
+	if err := d.NameFilter.validate(); err != nil {
+		return fmt.Errorf("address context discovery: %v", err)
+	}
+	return nil
+}
+
+func (r RetryConfig) validate() error {
+	if r.Attempts < 1 {
+		return fmt.Errorf("retry attempts must be at least 1")
+	}
+	if r.Backoff < 0 || r.MaxBackoff < 0 {
+		return fmt.Errorf("negative retry backoff")
+	}
+	return nil
+}
+
+func (b BreakerConfig) validate() error {
+	if b.FailureThreshold < 0 {
+		return fmt.Errorf("negative circuit_breaker failure_threshold")
+	}
+	if b.OpenDuration <= 0 {
+		return fmt.Errorf("circuit_breaker open_duration must be positive")
+	}
+	return nil
+}
+
+func (t Target) validatePassword() error {
+	if t.APIPass != "" && t.APIPassFile != "" {
+		return fmt.Errorf("only one of api_password and api_password_file may be given")
+	}
+	if _, err := t.Password(); err != nil {
+		return fmt.Errorf("unable to read api_password_file: %v", err)
+	}
+	return nil
+}
+
+func validateTargets(targets []Target) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("no targets defined")
+	}
+
+	names := make(map[string]bool)
+
+	for i, t := range targets {
+		if t.Name == "" {
+			return fmt.Errorf("target at index %d has no name", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("target %q is defined more than once", t.Name)
+		}
+		names[t.Name] = true
+
+		if len(t.APIURLs) == 0 {
+			return fmt.Errorf("target %q has no api_urls", t.Name)
+		}
+		if len(t.APIAddressContexts) == 0 {
+			return fmt.Errorf("target %q has no address_contexts", t.Name)
+		}
+		if t.APITimeout <= 0 {
+			return fmt.Errorf("target %q has a non-positive timeout", t.Name)
+		}
+		if err := t.validatePassword(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if _, err := t.TLS.Build(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if err := t.Auth.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if err := t.Retry.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if err := t.Discovery.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if err := t.CircuitBreaker.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if err := t.Filters.validate(); err != nil {
+			return fmt.Errorf("target %q: %v", t.Name, err)
+		}
+		if t.PollInterval < 0 {
+			return fmt.Errorf("target %q has a negative poll_interval", t.Name)
+		}
+		if t.TopologyCacheTTL < 0 {
+			return fmt.Errorf("target %q has a negative topology_cache_ttl", t.Name)
+		}
+		if t.Concurrency < 1 {
+			return fmt.Errorf("target %q must have a concurrency of at least 1", t.Name)
+		}
+		for c, timeout := range t.CollectorTimeouts {
+			if timeout <= 0 {
+				return fmt.Errorf("target %q has a non-positive timeout for collector %q", t.Name, c)
+			}
+		}
+		if t.SeriesLimit < 0 {
+			return fmt.Errorf("target %q has a negative series_limit", t.Name)
+		}
+		for c, limit := range t.CollectorSeriesLimits {
+			if limit < 0 {
+				return fmt.Errorf("target %q has a negative series limit for collector %q", t.Name, c)
+			}
+		}
+		for l := range t.Labels {
+			if !labelNameRegex.MatchString(l) {
+				return fmt.Errorf("target %q has invalid label name %q", t.Name, l)
+			}
+		}
+	}
+
+	return nil
+}
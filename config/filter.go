@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"sonus-metrics-exporter/lib"
+)
+
+var (
+	// anchoredRegexps caches compiled filter patterns, as filters are checked for every record of every scrape
+	anchoredRegexps      = make(map[string]*regexp.Regexp)
+	anchoredRegexpsMutex sync.Mutex
+)
+
+// NameFilter selects names by regular expressions matching whole names. A name is selected when it matches
+// Include, if given, and does not match Exclude.
+type NameFilter struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+}
+
+// Filters holds which records of a target are emitted as metrics
+type Filters struct {
+	Zones           NameFilter `yaml:"zones"`
+	TrunkGroups     NameFilter `yaml:"trunk_groups"`
+	IPInterfaces    NameFilter `yaml:"ip_interfaces"`
+	SipArsEndpoints NameFilter `yaml:"sipars_endpoints"`
+}
+
+// Matches reports whether the name is selected by the filter
+func (f NameFilter) Matches(name string) bool {
+	if f.Include != "" && !anchored(f.Include).MatchString(name) {
+		return false
+	}
+	if f.Exclude != "" && anchored(f.Exclude).MatchString(name) {
+		return false
+	}
+	return true
+}
+
+func (f NameFilter) validate() error {
+	if _, err := regexp.Compile(f.Include); err != nil {
+		return fmt.Errorf("invalid include: %v", err)
+	}
+	if _, err := regexp.Compile(f.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude: %v", err)
+	}
+	return nil
+}
+
+// Matches reports whether a record of the given kind should be emitted
+func (f Filters) Matches(kind, name string) bool {
+	switch kind {
+	case lib.FilterZone:
+		return f.Zones.Matches(name)
+	case lib.FilterTrunkGroup:
+		return f.TrunkGroups.Matches(name)
+	case lib.FilterIPInterface:
+		return f.IPInterfaces.Matches(name)
+	case lib.FilterSipArsEndpoint:
+		return f.SipArsEndpoints.Matches(name)
+	}
+	return true
+}
+
+func (f Filters) validate() error {
+	for kind, filter := range map[string]NameFilter{
+		"zones":            f.Zones,
+		"trunk_groups":     f.TrunkGroups,
+		"ip_interfaces":    f.IPInterfaces,
+		"sipars_endpoints": f.SipArsEndpoints,
+	} {
+		if err := filter.validate(); err != nil {
+			return fmt.Errorf("%s filter: %v", kind, err)
+		}
+	}
+	return nil
+}
+
+// anchored returns the compiled pattern, anchored to match whole names. The pattern must already be validated.
+func anchored(pattern string) *regexp.Regexp {
+	anchoredRegexpsMutex.Lock()
+	defer anchoredRegexpsMutex.Unlock()
+
+	r, ok := anchoredRegexps[pattern]
+	if !ok {
+		r = regexp.MustCompile("^(?:" + pattern + ")$")
+		anchoredRegexps[pattern] = r
+	}
+	return r
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret is a string which is redacted when it is logged or marshalled
+type Secret string
+
+const redactedSecret = "<secret>"
+
+// String redacts the secret, use string(s) to get its value
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// MarshalJSON redacts the secret
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML redacts the secret
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// secretFile holds the contents of a file read by ReadSecretFile, and when it was modified
+type secretFile struct {
+	modTime time.Time
+	size    int64
+	value   Secret
+}
+
+var (
+	secretFiles      = make(map[string]secretFile)
+	secretFilesMutex sync.Mutex
+)
+
+// ReadSecretFile returns the contents of the file with surrounding whitespace removed.
+// The file is only read again when it has been modified, so rotated secrets are picked up cheaply.
+func ReadSecretFile(path string) (Secret, error) {
+	secretFilesMutex.Lock()
+	defer secretFilesMutex.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if f, ok := secretFiles[path]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f.value, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	value := Secret(strings.TrimSpace(string(raw)))
+	secretFiles[path] = secretFile{modTime: info.ModTime(), size: info.Size(), value: value}
+
+	return value, nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import (
+	"sync/atomic"
+)
+
+// Store holds the active Config, allowing it to be swapped atomically when reloaded
+type Store struct {
+	current atomic.Pointer[Config]
+}
+
+// NewStore creates a Store holding the given Config
+func NewStore(c Config) *Store {
+	s := new(Store)
+	s.Set(c)
+	return s
+}
+
+// Get returns the active Config
+func (s *Store) Get() Config {
+	return *s.current.Load()
+}
+
+// Set replaces the active Config
+func (s *Store) Set(c Config) {
+	s.current.Store(&c)
+}
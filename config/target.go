@@ -0,0 +1,151 @@
+package config
+
+import (
+	"time"
+
+	"sonus-metrics-exporter/lib"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Target holds the configuration for a single SBC cluster
+type Target struct {
+	Name                  string                   `yaml:"name"`
+	APIURLs               []string                 `yaml:"api_urls"`
+	APIUser               string                   `yaml:"api_user"`
+	APIPass               Secret                   `yaml:"api_password"`
+	APIPassFile           string                   `yaml:"api_password_file"`
+	APIAddressContexts    []string                 `yaml:"address_contexts"`
+	Discovery             DiscoveryConfig          `yaml:"address_context_discovery"`
+	APITimeout            time.Duration            `yaml:"timeout"`
+	Concurrency           int                      `yaml:"concurrency"`
+	PollInterval          time.Duration            `yaml:"poll_interval"`
+	TopologyCacheTTL      time.Duration            `yaml:"topology_cache_ttl"`
+	TLS                   TLSConfig                `yaml:"tls"`
+	Auth                  AuthConfig               `yaml:"auth"`
+	Retry                 RetryConfig              `yaml:"retry"`
+	CircuitBreaker        BreakerConfig            `yaml:"circuit_breaker"`
+	Collectors            []string                 `yaml:"collectors"`
+	DisabledCollectors    []string                 `yaml:"disabled_collectors"`
+	CollectorTimeouts     map[string]time.Duration `yaml:"collector_timeouts"`
+	Filters               Filters                  `yaml:"filters"`
+	SeriesLimit           int                      `yaml:"series_limit"`
+	CollectorSeriesLimits map[string]int           `yaml:"collector_series_limits"`
+	Compatibility         lib.Compatibility        `yaml:"compatibility"`
+	Labels                map[string]string        `yaml:"labels"`
+
+	// Adhoc is set for SBC addresses given to /probe which are not configured targets.
+	// No state is kept between scrapes of ad-hoc targets, as any number of them may be requested.
+	Adhoc bool `yaml:"-"`
+}
+
+// DiscoveryConfig holds whether address contexts are listed from the SBC rather than configured,
+// and which of them are scraped
+type DiscoveryConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	NameFilter `yaml:",inline"`
+}
+
+// AuthConfig holds how the exporter authenticates to the SBC API
+type AuthConfig struct {
+	Mode       string        `yaml:"mode"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+// RetryConfig holds how failed API requests are retried
+type RetryConfig struct {
+	Attempts   int           `yaml:"attempts"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// BreakerConfig holds when requests to an SBC are stopped after repeated failures.
+// A FailureThreshold of 0 disables the circuit breaker.
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	OpenDuration     time.Duration `yaml:"open_duration"`
+}
+
+const (
+	// AuthModeBasic sends Basic auth credentials with every request
+	AuthModeBasic = "basic"
+	// AuthModeSession sends credentials once, then reuses the session cookie issued by the SBC
+	AuthModeSession = "session"
+)
+
+// newTarget returns a Target populated with the same defaults as the environment configuration
+func newTarget() Target {
+	return Target{
+		APIAddressContexts: []string{"default"},
+		APITimeout:         10 * time.Second,
+		Concurrency:        1,
+		Auth: AuthConfig{
+			Mode: AuthModeBasic,
+		},
+		Retry: RetryConfig{
+			Attempts:   3,
+			Backoff:    200 * time.Millisecond,
+			MaxBackoff: 2 * time.Second,
+		},
+		CircuitBreaker: BreakerConfig{
+			FailureThreshold: 5,
+			OpenDuration:     30 * time.Second,
+		},
+	}
+}
+
+// UnmarshalYAML applies the defaults before decoding, so omitted fields keep their default values
+func (t *Target) UnmarshalYAML(value *yaml.Node) error {
+	type plain Target
+
+	*t = newTarget()
+	return value.Decode((*plain)(t))
+}
+
+// Password returns the API password, read from the password file when one is configured
+func (t Target) Password() (Secret, error) {
+	if t.APIPassFile != "" {
+		return ReadSecretFile(t.APIPassFile)
+	}
+	return t.APIPass, nil
+}
+
+// CollectorTimeout returns how long the named collector's requests may take, or 0 when
+// they are only limited by the scrape timeout
+func (t Target) CollectorTimeout(name string) time.Duration {
+	for c, timeout := range t.CollectorTimeouts {
+		if lib.SameCollector(c, name) {
+			return timeout
+		}
+	}
+	return 0
+}
+
+// CollectorSeriesLimit returns how many series the named collector may emit in a scrape, or 0 when it is not limited
+func (t Target) CollectorSeriesLimit(name string) int {
+	for c, limit := range t.CollectorSeriesLimits {
+		if lib.SameCollector(c, name) {
+			return limit
+		}
+	}
+	return 0
+}
+
+// CollectorEnabled reports whether the named collector should run for this target.
+// All collectors are enabled when none are listed, except those that are disabled.
+func (t Target) CollectorEnabled(name string) bool {
+	for _, c := range t.DisabledCollectors {
+		if lib.SameCollector(c, name) {
+			return false
+		}
+	}
+	if len(t.Collectors) == 0 {
+		return true
+	}
+	for _, c := range t.Collectors {
+		if lib.SameCollector(c, name) {
+			return true
+		}
+	}
+	return false
+}
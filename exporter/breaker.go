@@ -0,0 +1,200 @@
+package exporter
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"sonus-metrics-exporter/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+var (
+	// breakers holds the circuit breaker of each API URL, keyed by target name and URL
+	breakers      = make(map[string]*breaker)
+	breakersMutex sync.Mutex
+
+	// breakerState is a gauge metric that reports the circuit breaker state of each API URL
+	breakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker for the API URL. 0 = closed, 1 = open, 2 = half-open",
+	}, []string{"target", "url"})
+
+	// requestRetries is a counter metric that tracks how often API requests to each target are retried
+	requestRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "request_retries_total",
+		Help:      "Number of times a failed API request was retried",
+	}, []string{"target"})
+)
+
+func init() {
+	prometheus.MustRegister(breakerState)
+	prometheus.MustRegister(requestRetries)
+}
+
+// breaker stops requests to an API URL after consecutive failures, so an SBC whose REST API is
+// failing is not sent more requests. After the open duration, a single trial request is let through,
+// and its result decides whether the breaker closes or opens again.
+type breaker struct {
+	target string
+	url    string
+
+	mutex    sync.Mutex
+	config   config.BreakerConfig
+	state    int
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// breakerFor returns the circuit breaker for the API URL of the target.
+// Ad-hoc targets get a disabled breaker, as nothing is kept between their scrapes.
+func breakerFor(t config.Target, url string) *breaker {
+	if t.Adhoc {
+		return &breaker{target: t.Name, url: url}
+	}
+
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	key := t.Name + "\x00" + url
+
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{target: t.Name, url: url}
+		breakers[key] = b
+		breakerState.WithLabelValues(t.Name, url).Set(breakerClosed)
+	}
+
+	b.mutex.Lock()
+	b.config = t.CircuitBreaker
+	b.mutex.Unlock()
+
+	return b
+}
+
+// pruneBreakers drops the circuit breakers and their state of targets which are no longer configured
+func pruneBreakers(c config.Config) {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+
+	for key, b := range breakers {
+		if _, ok := c.Target(b.target); !ok {
+			delete(breakers, key)
+			breakerState.DeleteLabelValues(b.target, b.url)
+			requestRetries.DeleteLabelValues(b.target)
+		}
+	}
+}
+
+// apiBaseOf returns which of the target's API URLs the request URL belongs to
+func apiBaseOf(t config.Target, url string) string {
+	base := url
+	for _, u := range t.APIURLs {
+		if strings.HasPrefix(url, u) && (base == url || len(u) > len(base)) {
+			base = u
+		}
+	}
+	return base
+}
+
+// allow reports whether a request may be made to the API URL
+func (b *breaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.config.FailureThreshold == 0 {
+		return true
+	}
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.config.OpenDuration {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.trial = true
+		return true
+	case breakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+
+	return true
+}
+
+// record updates the breaker with the result of a request that was allowed
+func (b *breaker) record(failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.config.FailureThreshold == 0 {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		b.trial = false
+		if b.state != breakerClosed {
+			log.Infof("Circuit breaker for API_URL %q of target %q closed", b.url, b.target)
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		if b.state != breakerOpen {
+			log.Warnf("Circuit breaker for API_URL %q of target %q opened after %d failures", b.url, b.target, b.failures)
+		}
+		b.trial = false
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+// abandon releases the trial request of a half-open breaker without recording a result,
+// for requests cancelled before they finished
+func (b *breaker) abandon() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.trial = false
+}
+
+func (b *breaker) setState(state int) {
+	b.state = state
+	breakerState.WithLabelValues(b.target, b.url).Set(float64(state))
+}
+
+// retryBackoff returns how long to wait before the next attempt, doubling the backoff on each
+// attempt up to the maximum. Half of the wait is random, so retries from concurrent requests are spread out.
+func retryBackoff(r config.RetryConfig, attempt int) time.Duration {
+	backoff := r.Backoff
+	for i := 1; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	if backoff <= 1 {
+		return backoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+}
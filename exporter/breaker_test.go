@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"sonus-metrics-exporter/config"
+)
+
+func TestBreaker(t *testing.T) {
+	// Each step is applied in order: allow and deny expect allow() to return true and false,
+	// fail and succeed record a result, expire ends the open duration and abandon drops the trial
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []string
+		state     int
+	}{
+		{"disabled", 0, []string{"allow", "fail", "fail", "fail", "allow"}, breakerClosed},
+		{"below threshold", 3, []string{"allow", "fail", "allow", "fail", "allow"}, breakerClosed},
+		{"success resets failures", 2, []string{"fail", "succeed", "fail", "allow"}, breakerClosed},
+		{"opens at threshold", 2, []string{"fail", "fail", "deny"}, breakerOpen},
+		{"single trial when half-open", 2, []string{"fail", "fail", "expire", "allow", "deny"}, breakerHalfOpen},
+		{"trial success closes", 2, []string{"fail", "fail", "expire", "allow", "succeed", "allow", "allow"}, breakerClosed},
+		{"trial failure reopens", 2, []string{"fail", "fail", "expire", "allow", "fail", "deny"}, breakerOpen},
+		{"abandoned trial is released", 2, []string{"fail", "fail", "expire", "allow", "abandon", "allow", "deny"}, breakerHalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &breaker{
+				target: "test",
+				url:    tt.name,
+				config: config.BreakerConfig{FailureThreshold: tt.threshold, OpenDuration: time.Hour},
+			}
+
+			for i, step := range tt.steps {
+				switch step {
+				case "allow", "deny":
+					if got := b.allow(); got != (step == "allow") {
+						t.Fatalf("step %d: allow() = %v, want %v", i, got, step == "allow")
+					}
+				case "fail":
+					b.record(true)
+				case "succeed":
+					b.record(false)
+				case "expire":
+					b.openedAt = b.openedAt.Add(-time.Hour)
+				case "abandon":
+					b.abandon()
+				}
+			}
+
+			if b.state != tt.state {
+				t.Errorf("state = %d, want %d", b.state, tt.state)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		retry    config.RetryConfig
+		attempt  int
+		min, max time.Duration
+	}{
+		{"first attempt", config.RetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"doubles", config.RetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped", config.RetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}, 10, 500 * time.Millisecond, time.Second},
+		{"backoff above max", config.RetryConfig{Backoff: 5 * time.Second, MaxBackoff: time.Second}, 1, 500 * time.Millisecond, time.Second},
+		{"zero", config.RetryConfig{}, 2, 0, 0},
+		{"one nanosecond", config.RetryConfig{Backoff: 1, MaxBackoff: 1}, 1, 1, 1},
+		{"two nanoseconds", config.RetryConfig{Backoff: 2, MaxBackoff: 2}, 1, 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The wait is partly random, so check it stays in bounds over many draws
+			for i := 0; i < 100; i++ {
+				got := retryBackoff(tt.retry, tt.attempt)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("retryBackoff() = %v, want between %v and %v", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
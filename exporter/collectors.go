@@ -0,0 +1,215 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// serverStatusCollector and zoneStatusCollector name the metrics built in to every scrape
+	serverStatusCollector = "ServerStatus"
+	zoneStatusCollector   = "ZoneStatus"
+)
+
+var builtinCollectors = []string{serverStatusCollector, zoneStatusCollector}
+
+// builtinHelp describes the built in collectors when listing collectors
+var builtinHelp = map[string]string{
+	serverStatusCollector: "Redundancy role, sync status and uptime of each server",
+	zoneStatusCollector:   "Calls in use and configured in each zone",
+}
+
+// ValidateMetrics checks the registered collectors for missing functions, duplicate names, and
+// descriptors which clash with each other or with the exporter's own metrics
+func ValidateMetrics(metrics []lib.SonusMetric) error {
+	var builtin []*prometheus.Desc
+
+	for _, descs := range []map[string]*prometheus.Desc{serverStatusMetrics, zoneStatusMetrics, scrapeMetrics, snapshotMetrics, healthMetrics} {
+		for _, d := range descs {
+			builtin = append(builtin, d)
+		}
+	}
+
+	for _, m := range metrics {
+		for _, b := range builtinCollectors {
+			if lib.SameCollector(b, m.Name) {
+				return fmt.Errorf("collector %q has the name of a built in collector", m.Name)
+			}
+		}
+	}
+
+	return lib.Validate(metrics, builtin...)
+}
+
+// ListCollectors writes the name, default state, repetition and help of every collector
+func ListCollectors(w io.Writer, metrics []lib.SonusMetric) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NAME\tDEFAULT\tREPEATED\tDESCRIPTION")
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", serverStatusCollector, "enabled", lib.RepeatNone, builtinHelp[serverStatusCollector])
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", zoneStatusCollector, "enabled", lib.RepeatPerAddressContext, builtinHelp[zoneStatusCollector])
+	for _, m := range metrics {
+		state := "enabled"
+		if m.DisabledByDefault {
+			state = "disabled"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Name, state, m.Repetition, m.Help)
+	}
+
+	return tw.Flush()
+}
+
+// ValidateCollectors ensures every collector named in the target configuration exists
+func ValidateCollectors(metrics []lib.SonusMetric, c config.Config) error {
+	for _, t := range c.Targets {
+		for _, name := range append(t.Collectors, t.DisabledCollectors...) {
+			if !collectorExists(metrics, name) {
+				return fmt.Errorf("target %q refers to unknown collector %q", t.Name, name)
+			}
+		}
+		for name := range t.CollectorTimeouts {
+			if isBuiltinCollector(name) {
+				return fmt.Errorf("target %q has a timeout for built in collector %q, which is always collected within the scrape timeout", t.Name, name)
+			}
+			if !collectorExists(metrics, name) {
+				return fmt.Errorf("target %q has a timeout for unknown collector %q", t.Name, name)
+			}
+		}
+		for name := range t.CollectorSeriesLimits {
+			if isBuiltinCollector(name) {
+				return fmt.Errorf("target %q has a series limit for built in collector %q, whose series are not limited", t.Name, name)
+			}
+			if !collectorExists(metrics, name) {
+				return fmt.Errorf("target %q has a series limit for unknown collector %q", t.Name, name)
+			}
+		}
+		if err := validateLabels(metrics, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLabels ensures the target's static labels can be applied to every metric the exporter describes,
+// by registering all of the collectors with the labels applied
+func validateLabels(metrics []lib.SonusMetric, t config.Target) error {
+	ex := &Exporter{
+		Metrics: metrics,
+		Target:  config.Target{Name: t.Name},
+	}
+	if err := prometheus.WrapRegistererWith(t.Labels, prometheus.NewRegistry()).Register(ex); err != nil {
+		return fmt.Errorf("target %q has labels which clash with the exporter's labels: %v", t.Name, err)
+	}
+	return nil
+}
+
+// isBuiltinCollector reports whether name is one of the collectors built in to every scrape
+func isBuiltinCollector(name string) bool {
+	for _, b := range builtinCollectors {
+		if lib.SameCollector(b, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectorExists reports whether name is a built in collector or one of the metrics
+func collectorExists(metrics []lib.SonusMetric, name string) bool {
+	if isBuiltinCollector(name) {
+		return true
+	}
+	for _, m := range metrics {
+		if lib.SameCollector(m.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectParam returns the collectors requested with collect[] query parameters, in the same
+// way as the node exporter
+func collectParam(metrics []lib.SonusMetric, r *http.Request) ([]string, error) {
+	collect := r.URL.Query()["collect[]"]
+
+	for _, name := range collect {
+		if !collectorExists(metrics, name) {
+			return nil, fmt.Errorf("Unknown collector %q", name)
+		}
+	}
+
+	return collect, nil
+}
+
+// enabledMetrics filters metrics down to the collectors enabled for the target, and named in collect when it is not empty
+func enabledMetrics(metrics []lib.SonusMetric, target config.Target, collect []string) []lib.SonusMetric {
+	var enabled []lib.SonusMetric
+
+	for _, m := range metrics {
+		if !target.CollectorEnabled(m.Name) || !inCollect(collect, m.Name) {
+			continue
+		}
+		// Collectors disabled by default must be asked for by name
+		if m.DisabledByDefault && !namedIn(target.Collectors, m.Name) && !namedIn(collect, m.Name) {
+			continue
+		}
+		enabled = append(enabled, m)
+	}
+
+	return enabled
+}
+
+// inCollect reports whether the collector is requested, where an empty collect requests everything
+func inCollect(collect []string, name string) bool {
+	if len(collect) == 0 {
+		return true
+	}
+	for _, c := range collect {
+		if lib.SameCollector(c, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// namedIn reports whether name is one of names
+func namedIn(names []string, name string) bool {
+	return len(names) > 0 && inCollect(names, name)
+}
+
+// collectorEnabled reports whether the named collector runs in this scrape
+func (e *Exporter) collectorEnabled(name string) bool {
+	return e.Target.CollectorEnabled(name) && inCollect(e.requested, name)
+}
+
+// enabledCollectors lists the names of the built in and metric collectors run in this scrape
+func (e *Exporter) enabledCollectors() []string {
+	var names []string
+
+	for _, b := range builtinCollectors {
+		if e.collectorEnabled(b) {
+			names = append(names, b)
+		}
+	}
+	for _, m := range e.Metrics {
+		names = append(names, m.Name)
+	}
+
+	return names
+}
+
+// hasRepetition reports whether any of the metrics in this scrape is repeated in the given way
+func (e *Exporter) hasRepetition(r lib.Repetition) bool {
+	for _, m := range e.Metrics {
+		if m.Repetition == r {
+			return true
+		}
+	}
+	return false
+}
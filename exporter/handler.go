@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// scrapeTimeoutOffset is taken from the Prometheus scrape timeout to leave time to send the results
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// MetricsHandler returns a handler which scrapes the default target, along with the
+// exporter's own metrics from the default registry.
+func MetricsHandler(metrics []lib.SonusMetric, store *config.Store) http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		collect, err := collectParam(metrics, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := scrapeContext(r)
+		defer cancel()
+
+		registry, err := newTargetRegistry(ctx, metrics, store.Get().DefaultTarget(), collect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}))
+}
+
+// ProbeHandler returns a handler which scrapes the SBC given in the target query parameter.
+// Each request builds its own Exporter and registry, so one exporter can serve many SBCs
+// in the same way as the blackbox exporter.
+//
+// The target is looked up by name in the configuration. When ad-hoc targets are allowed, it may instead
+// be a list of SBC addresses scraped using the default target's settings, without its credentials.
+func ProbeHandler(metrics []lib.SonusMetric, store *config.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := store.Get()
+
+		targetParam := r.URL.Query().Get("target")
+		if targetParam == "" {
+			http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		target, ok := c.Target(targetParam)
+		if !ok {
+			if !c.AllowAdhocTargets {
+				http.Error(w, fmt.Sprintf("Unknown target %q", targetParam), http.StatusNotFound)
+				return
+			}
+			target = adhocTarget(c.DefaultTarget(), targetParam)
+		}
+
+		collect, err := collectParam(metrics, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Infof("Probing target %q", target.Name)
+
+		ctx, cancel := scrapeContext(r)
+		defer cancel()
+
+		registry, err := newTargetRegistry(ctx, metrics, target, collect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+// newTargetRegistry creates a registry holding an Exporter for the target's enabled collectors,
+// with the target's static labels applied to every metric. When collect is not empty, only the
+// collectors it names are run. Collection stops when ctx is done.
+func newTargetRegistry(ctx context.Context, metrics []lib.SonusMetric, target config.Target, collect []string) (*prometheus.Registry, error) {
+	ex := &Exporter{
+		Metrics:   enabledMetrics(metrics, target, collect),
+		Target:    target,
+		requested: collect,
+		poller:    pollerFor(target),
+		scrapeCtx: ctx,
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := prometheus.WrapRegistererWith(target.Labels, registry).Register(ex); err != nil {
+		return nil, fmt.Errorf("unable to register the metrics of target %q: %v", target.Name, err)
+	}
+
+	return registry, nil
+}
+
+// scrapeContext returns a context which is done shortly before Prometheus gives up on the scrape,
+// leaving time for the partial results to be sent. Without the scrape timeout header, it is done
+// when the request is cancelled.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	rawTimeout := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if rawTimeout == "" {
+		return context.WithCancel(r.Context())
+	}
+
+	seconds, err := strconv.ParseFloat(rawTimeout, 64)
+	if err != nil || seconds <= 0 {
+		log.Warnf("Ignoring invalid X-Prometheus-Scrape-Timeout-Seconds header %q", rawTimeout)
+		return context.WithCancel(r.Context())
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// adhocTarget returns a target for SBC addresses which are not configured, using the default target's settings.
+// Anyone able to reach the exporter can choose the addresses, so the configured credentials are never sent to them.
+func adhocTarget(defaults config.Target, addresses string) config.Target {
+	t := defaults
+	t.Name = addresses
+	t.APIURLs = targetToURLs(addresses)
+	t.APIUser = ""
+	t.APIPass = ""
+	t.APIPassFile = ""
+	t.Auth = config.AuthConfig{Mode: config.AuthModeBasic}
+	t.TLS.CertFile = ""
+	t.TLS.KeyFile = ""
+	t.Labels = nil
+	t.Adhoc = true
+	return t
+}
+
+// targetToURLs converts a comma-separated list of SBC addresses into API URLs.
+// Bare hosts are expanded to https://{host}/api, full URLs are used as given.
+func targetToURLs(target string) []string {
+	var urls []string
+
+	for _, t := range strings.Split(target, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !strings.Contains(t, "://") {
+			t = fmt.Sprintf("https://%s/api", t)
+		}
+		urls = append(urls, t)
+	}
+
+	return urls
+}
@@ -0,0 +1,219 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	healthProbeInitialBackoff = 5 * time.Second
+	healthProbeMaxBackoff     = 5 * time.Minute
+)
+
+var (
+	// healthTrackers holds the API URL health of each target, keyed by target name
+	healthTrackers      = make(map[string]*urlHealth)
+	healthTrackersMutex sync.Mutex
+)
+
+var healthMetrics = map[string]*prometheus.Desc{
+	"API_URL_Healthy": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "api", "url_healthy"),
+		"Whether the API URL answered its last serverStatus request. 1 = healthy",
+		[]string{"url"}, nil,
+	),
+	"API_Failovers": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "api", "failovers_total"),
+		"Number of times the active API URL changed to another URL",
+		nil, nil,
+	),
+}
+
+// urlHealth tracks which of a target's API URLs are answering, so scrapes go straight to the last good URL
+// rather than waiting for failed URLs to time out. Failed URLs are probed in the background until they recover.
+type urlHealth struct {
+	mutex     sync.Mutex
+	urls      []string
+	target    config.Target
+	active    string
+	failed    map[string]bool
+	probing   map[string]bool
+	failovers uint64
+	stop      chan struct{}
+}
+
+// healthFor returns the health tracker for the target, replacing it when the target's URLs change.
+// Ad-hoc targets get a tracker for the scrape alone, which does not probe failed URLs.
+func healthFor(t config.Target) *urlHealth {
+	if t.Adhoc {
+		return newURLHealth(t)
+	}
+
+	healthTrackersMutex.Lock()
+	defer healthTrackersMutex.Unlock()
+
+	h, ok := healthTrackers[t.Name]
+	if ok && reflect.DeepEqual(h.urls, t.APIURLs) {
+		h.mutex.Lock()
+		h.target = t
+		h.mutex.Unlock()
+		return h
+	}
+	if ok {
+		close(h.stop)
+	}
+
+	h = newURLHealth(t)
+	healthTrackers[t.Name] = h
+
+	return h
+}
+
+func newURLHealth(t config.Target) *urlHealth {
+	return &urlHealth{
+		urls:    t.APIURLs,
+		target:  t,
+		failed:  make(map[string]bool),
+		probing: make(map[string]bool),
+		stop:    make(chan struct{}),
+	}
+}
+
+// pruneHealthTrackers stops tracking targets which are no longer configured
+func pruneHealthTrackers(c config.Config) {
+	healthTrackersMutex.Lock()
+	defer healthTrackersMutex.Unlock()
+
+	for name, h := range healthTrackers {
+		if _, ok := c.Target(name); !ok {
+			close(h.stop)
+			delete(healthTrackers, name)
+		}
+	}
+}
+
+// candidates returns the URLs to try in order: the last good URL first, then the other healthy URLs.
+// Failed URLs are left to the background prober, unless every URL has failed, in which case all are tried.
+func (h *urlHealth) candidates() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var urls []string
+
+	if h.active != "" && !h.failed[h.active] {
+		urls = append(urls, h.active)
+	}
+	for _, url := range h.urls {
+		if url != h.active && !h.failed[url] {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return h.urls
+	}
+
+	return urls
+}
+
+// succeeded records the URL as healthy and active, counting a failover when the active URL changes
+func (h *urlHealth) succeeded(url string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.active != "" && h.active != url {
+		log.Warnf("Target %q failed over from API_URL %q to %q", h.target.Name, h.active, url)
+		h.failovers++
+	}
+	h.active = url
+	delete(h.failed, url)
+}
+
+// fail records the URL as unhealthy and starts probing it in the background
+func (h *urlHealth) fail(url string, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.failed[url] = true
+
+	if !h.probing[url] && !h.target.Adhoc {
+		h.probing[url] = true
+		log.Warnf("API_URL %q of target %q failed, probing in the background. Error: %v", url, h.target.Name, err)
+		go h.probe(url)
+	}
+}
+
+// probe requests serverStatus from the URL with exponential backoff, until it answers or the tracker is replaced
+func (h *urlHealth) probe(url string) {
+	backoff := healthProbeInitialBackoff
+
+	defer func() {
+		h.mutex.Lock()
+		delete(h.probing, url)
+		h.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := h.check(url); err != nil {
+			backoff *= 2
+			if backoff > healthProbeMaxBackoff {
+				backoff = healthProbeMaxBackoff
+			}
+			log.Infof("API_URL %q of target %q is still failing, next probe in %v. Error: %v", url, h.target.Name, backoff, err)
+			continue
+		}
+
+		log.Infof("API_URL %q of target %q has recovered", url, h.target.Name)
+		h.mutex.Lock()
+		delete(h.failed, url)
+		h.mutex.Unlock()
+		return
+	}
+}
+
+// check makes a single serverStatus request to the URL
+func (h *urlHealth) check(url string) error {
+	h.mutex.Lock()
+	t := h.target
+	h.mutex.Unlock()
+
+	api, err := newAPIClient(t)
+	if err != nil {
+		return err
+	}
+	defer api.client.CloseIdleConnections()
+
+	response, err := doHTTPRequestOnce(context.Background(), api, getServerStatusURL(url))
+	if err != nil {
+		return err
+	}
+	if response.response.StatusCode != 200 {
+		return fmt.Errorf("non-200 HTTP response (%d)", response.response.StatusCode)
+	}
+	return nil
+}
+
+// collect emits the health of each URL and the number of failovers
+func (h *urlHealth) collect(ch chan<- prometheus.Metric) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, url := range h.urls {
+		ch <- prometheus.MustNewConstMetric(healthMetrics["API_URL_Healthy"], prometheus.GaugeValue, lib.BoolToFloat(!h.failed[url]), url)
+	}
+	ch <- prometheus.MustNewConstMetric(healthMetrics["API_Failovers"], prometheus.CounterValue, float64(h.failovers))
+}
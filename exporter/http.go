@@ -1,10 +1,14 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
+	"time"
+
+	"sonus-metrics-exporter/config"
 )
 
 // httpResponse struct is used to store http.Response and associated data
@@ -14,28 +18,138 @@ type httpResponse struct {
 	body     *[]byte
 }
 
-// doHTTPRequest makes an individual HTTP request and returns a *httpResponse
-func doHTTPRequest(client *http.Client, url string, user string, pass string) (*httpResponse, error) {
-	log.Infof("Fetching %q \n", url)
+// apiClient holds what is needed to make requests to a target's SBC API during a single scrape
+type apiClient struct {
+	client   *http.Client
+	target   config.Target
+	password config.Secret
+	session  *session
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// idleConnTimeout closes keep-alive connections left behind by requests which finished after their scrape ended
+const idleConnTimeout = 30 * time.Second
 
+// newHTTPTransport creates the http.Transport used for a single scrape of a target.
+// The CA and client certificate files are read each time, so replaced certificates are picked up.
+func newHTTPTransport(c config.TLSConfig) (*http.Transport, error) {
+	tlsConfig, err := c.Build()
 	if err != nil {
 		return nil, err
 	}
+	return &http.Transport{TLSClientConfig: tlsConfig, IdleConnTimeout: idleConnTimeout}, nil
+}
+
+// newAPIClient creates the apiClient used for a single scrape of a target.
+// With session authentication, the session is shared with previous scrapes of the target.
+func newAPIClient(t config.Target) (*apiClient, error) {
+	transport, err := newHTTPTransport(t.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	// The password file is checked on each scrape, so rotated credentials are used without a restart
+	password, err := t.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	api := &apiClient{
+		client:   &http.Client{Transport: transport, Timeout: t.APITimeout},
+		target:   t,
+		password: password,
+	}
+
+	if t.Auth.Mode == config.AuthModeSession {
+		api.session = sessionFor(t, password)
+		api.client.Jar = api.session.jar
+	}
+
+	return api, nil
+}
+
+// doHTTPRequest makes an individual HTTP request and returns a *httpResponse.
+// Connection errors and 5xx responses are retried with backoff, and count towards the circuit breaker of the API URL.
+// The request is abandoned when ctx is done.
+func doHTTPRequest(ctx context.Context, api *apiClient, url string) (*httpResponse, error) {
+	return doHTTPRequestAttempts(ctx, api, url, api.target.Retry.Attempts)
+}
+
+// doHTTPRequestOnce makes a request without retrying it, for requests where trying the next API URL is the retry
+func doHTTPRequestOnce(ctx context.Context, api *apiClient, url string) (*httpResponse, error) {
+	return doHTTPRequestAttempts(ctx, api, url, 1)
+}
+
+func doHTTPRequestAttempts(ctx context.Context, api *apiClient, url string, attempts int) (*httpResponse, error) {
+	log.Infof("Fetching %q \n", url)
+
+	b := breakerFor(api.target, apiBaseOf(api.target, url))
+	if !b.allow() {
+		return nil, fmt.Errorf("circuit breaker for API_URL %q is open", b.url)
+	}
 
-	req.SetBasicAuth(user, pass)
+	var (
+		response *httpResponse
+		err      error
+	)
 
-	resp, err := client.Do(req)
+	for attempt := 1; ; attempt++ {
+		response, err = api.fetch(ctx, url)
+		if !requestFailed(response, err) || attempt >= attempts || ctx.Err() != nil {
+			break
+		}
+
+		backoff := retryBackoff(api.target.Retry, attempt)
+		log.Infof("Retrying %q in %v after attempt %d failed", url, backoff, attempt)
+		if !api.target.Adhoc {
+			requestRetries.WithLabelValues(api.target.Name).Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+	}
+
+	// Running out of scrape time says nothing about the health of the SBC
+	if ctx.Err() != nil {
+		b.abandon()
+	} else {
+		b.record(requestFailed(response, err))
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode == 404 {
+	if response.response.StatusCode == 404 {
 		return nil, fmt.Errorf("Received 404 status from Sonus API, ensure the URL is correct. ")
 	}
 
+	return response, nil
+}
+
+// requestFailed reports whether the request failed in a way that may succeed when retried
+func requestFailed(response *httpResponse, err error) bool {
+	return err != nil || response.response.StatusCode >= 500
+}
+
+// fetch makes a single attempt at a request, reading the whole body
+func (api *apiClient) fetch(ctx context.Context, url string) (*httpResponse, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	if api.session != nil {
+		resp, err = api.session.do(ctx, api, url)
+	} else {
+		resp, err = api.do(ctx, url, true)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
 	// Read the body to a byte array so it can be used elsewhere
 	body, err := ioutil.ReadAll(resp.Body)
 
@@ -47,3 +161,18 @@ func doHTTPRequest(client *http.Client, url string, user string, pass string) (*
 
 	return &httpResponse{url, resp, &body}, nil
 }
+
+// do performs a GET request, sending Basic auth credentials when asked to
+func (api *apiClient) do(ctx context.Context, url string, withCredentials bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if withCredentials && api.target.APIUser != "" {
+		req.SetBasicAuth(api.target.APIUser, string(api.password))
+	}
+
+	return api.client.Do(req)
+}
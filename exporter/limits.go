@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// seriesDropped is a counter metric that tracks series not emitted because a collector or target went over its limit
+var seriesDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sonus",
+	Subsystem: "exporter",
+	Name:      "series_dropped_total",
+	Help:      "Number of series not emitted because a collector or target exceeded its series limit",
+}, []string{"collector"})
+
+func init() {
+	prometheus.MustRegister(seriesDropped)
+}
+
+// seriesLimiter holds back the series of limited collectors until the end of a scrape, then emits those within
+// the limits. Series are grouped into records by their label values and kept in order of those values, so whole
+// records are kept or dropped, and the same records are dropped on every scrape.
+type seriesLimiter struct {
+	target config.Target
+	owners map[*prometheus.Desc]string
+	held   map[string][]heldSeries
+}
+
+type heldSeries struct {
+	labels []*dto.LabelPair
+	desc   string
+	metric prometheus.Metric
+}
+
+// newSeriesLimiter returns a limiter for the target's series limits, or nil when it has none
+func newSeriesLimiter(t config.Target, metrics []lib.SonusMetric) *seriesLimiter {
+	l := &seriesLimiter{
+		target: t,
+		owners: make(map[*prometheus.Desc]string),
+		held:   make(map[string][]heldSeries),
+	}
+
+	limited := t.SeriesLimit > 0
+	for _, m := range metrics {
+		if t.CollectorSeriesLimit(m.Name) > 0 {
+			limited = true
+		}
+		for _, desc := range m.APIMetrics {
+			l.owners[desc] = m.Name
+		}
+	}
+	if !limited {
+		return nil
+	}
+
+	return l
+}
+
+// hold keeps back the series when its collector is limited, reporting whether it did
+func (l *seriesLimiter) hold(m prometheus.Metric) bool {
+	if l == nil {
+		return false
+	}
+
+	collector, ok := l.owners[m.Desc()]
+	if !ok || (l.target.SeriesLimit == 0 && l.target.CollectorSeriesLimit(collector) == 0) {
+		return false
+	}
+
+	var d dto.Metric
+	if err := m.Write(&d); err != nil {
+		log.Errorf("Unable to read the labels of a series of collector %q: %v", collector, err)
+	}
+
+	l.held[collector] = append(l.held[collector], heldSeries{labels: d.Label, desc: m.Desc().String(), metric: m})
+	return true
+}
+
+// flush emits the held series within the limits, counting those dropped. Each collector is first cut to its own
+// limit, then the target limit is shared out evenly, with collectors needing less than an even share leaving the
+// rest to the others.
+func (l *seriesLimiter) flush(ch chan<- prometheus.Metric) {
+	if l == nil {
+		return
+	}
+
+	var (
+		collectors []string
+		dropped    []string
+		records    = make(map[string][][]heldSeries)
+		wanted     = make(map[string]int)
+	)
+
+	for collector, series := range l.held {
+		collectors = append(collectors, collector)
+		records[collector] = groupRecords(series)
+		wanted[collector] = recordsWithin(records[collector], l.target.CollectorSeriesLimit(collector))
+	}
+	sort.Strings(collectors)
+
+	allowed := wanted
+	if l.target.SeriesLimit > 0 {
+		allowed = shareLimit(records, wanted, l.target.SeriesLimit)
+	}
+
+	for _, collector := range collectors {
+		kept := 0
+		for _, record := range records[collector] {
+			if kept+len(record) > allowed[collector] {
+				break
+			}
+			for _, s := range record {
+				ch <- s.metric
+			}
+			kept += len(record)
+		}
+
+		if n := len(l.held[collector]) - kept; n > 0 {
+			seriesDropped.WithLabelValues(collector).Add(float64(n))
+			dropped = append(dropped, fmt.Sprintf("%s=%d", collector, n))
+		}
+	}
+
+	if len(dropped) > 0 {
+		log.Warnf("Scrape of target %q dropped series over its series limits: %s", l.target.Name, strings.Join(dropped, " "))
+	}
+}
+
+// groupRecords groups a collector's series into records, identified by the values of the labels every one of its
+// series has, such as the name of a trunk group. Records are sorted by those values, and their series by descriptor
+// and the remaining labels.
+func groupRecords(series []heldSeries) [][]heldSeries {
+	common := make(map[string]int)
+	for _, s := range series {
+		for _, lp := range s.labels {
+			common[lp.GetName()]++
+		}
+	}
+
+	type keyedSeries struct {
+		record, key string
+		series      heldSeries
+	}
+
+	keyed := make([]keyedSeries, len(series))
+	for i, s := range series {
+		var record, key strings.Builder
+		key.WriteString(s.desc)
+		for _, lp := range s.labels {
+			if common[lp.GetName()] == len(series) {
+				record.WriteString(lp.GetName() + "=" + lp.GetValue() + "\xff")
+			}
+			key.WriteString("\xff" + lp.GetName() + "=" + lp.GetValue())
+		}
+		keyed[i] = keyedSeries{record.String(), key.String(), s}
+	}
+	sort.Slice(keyed, func(i, j int) bool {
+		if keyed[i].record != keyed[j].record {
+			return keyed[i].record < keyed[j].record
+		}
+		return keyed[i].key < keyed[j].key
+	})
+
+	var records [][]heldSeries
+	for i, k := range keyed {
+		if i == 0 || k.record != keyed[i-1].record {
+			records = append(records, nil)
+		}
+		records[len(records)-1] = append(records[len(records)-1], k.series)
+	}
+
+	return records
+}
+
+// recordsWithin returns the number of series in the leading whole records which fit within the limit,
+// where a limit of 0 is unlimited
+func recordsWithin(records [][]heldSeries, limit int) int {
+	n := 0
+	for _, record := range records {
+		if limit > 0 && n+len(record) > limit {
+			break
+		}
+		n += len(record)
+	}
+	return n
+}
+
+// shareLimit shares the limit out between collectors wanting the given number of series. Collectors wanting the
+// least are served first, each getting the whole records which fit in an even share of what is left, so what they
+// do not use goes to the collectors after them.
+func shareLimit(records map[string][][]heldSeries, wanted map[string]int, limit int) map[string]int {
+	var collectors []string
+	for c := range wanted {
+		collectors = append(collectors, c)
+	}
+	sort.Slice(collectors, func(i, j int) bool {
+		if wanted[collectors[i]] != wanted[collectors[j]] {
+			return wanted[collectors[i]] < wanted[collectors[j]]
+		}
+		return collectors[i] < collectors[j]
+	})
+
+	shares := make(map[string]int)
+	for i, c := range collectors {
+		share := 0
+		if even := limit / (len(collectors) - i); even > 0 {
+			share = recordsWithin(records[c], even)
+		}
+		if wanted[c] < share {
+			share = wanted[c]
+		}
+		shares[c] = share
+		limit -= share
+	}
+
+	return shares
+}
@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	limitTestBandwidth = prometheus.NewDesc("test_bandwidth", "", []string{"name"}, nil)
+	limitTestState     = prometheus.NewDesc("test_state", "", []string{"name", "state"}, nil)
+	limitTestUsage     = prometheus.NewDesc("test_usage", "", []string{"name", "direction"}, nil)
+	limitTestEndpoint  = prometheus.NewDesc("test_endpoint", "", []string{"address"}, nil)
+	limitTestServer    = prometheus.NewDesc("test_server", "", []string{"server"}, nil)
+)
+
+// limitTestMetrics has a collector emitting four series for each of its records, and two emitting one
+var limitTestMetrics = []lib.SonusMetric{
+	{Name: "Group", APIMetrics: map[string]*prometheus.Desc{"Bandwidth": limitTestBandwidth, "State": limitTestState, "Usage": limitTestUsage}},
+	{Name: "Endpoint", APIMetrics: map[string]*prometheus.Desc{"Endpoint": limitTestEndpoint}},
+	{Name: "Server", APIMetrics: map[string]*prometheus.Desc{"Server": limitTestServer}},
+}
+
+// limitTestSeries emits the series of records in reverse order, so the limiter has to sort them
+func limitTestSeries(groups, endpoints, servers int) []prometheus.Metric {
+	var series []prometheus.Metric
+	for i := groups; i > 0; i-- {
+		name := fmt.Sprintf("g%d", i)
+		series = append(series,
+			prometheus.MustNewConstMetric(limitTestUsage, prometheus.GaugeValue, 1, name, "outbound"),
+			prometheus.MustNewConstMetric(limitTestUsage, prometheus.GaugeValue, 1, name, "inbound"),
+			prometheus.MustNewConstMetric(limitTestState, prometheus.GaugeValue, 1, name, "inService"),
+			prometheus.MustNewConstMetric(limitTestBandwidth, prometheus.GaugeValue, 1, name),
+		)
+	}
+	for i := endpoints; i > 0; i-- {
+		series = append(series, prometheus.MustNewConstMetric(limitTestEndpoint, prometheus.GaugeValue, 1, fmt.Sprintf("e%d", i)))
+	}
+	for i := servers; i > 0; i-- {
+		series = append(series, prometheus.MustNewConstMetric(limitTestServer, prometheus.GaugeValue, 1, fmt.Sprintf("s%d", i)))
+	}
+	return series
+}
+
+func TestSeriesLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    config.Target
+		series    []prometheus.Metric
+		survivors map[string][]string
+	}{
+		{
+			name:   "collector limit keeps whole records",
+			target: config.Target{CollectorSeriesLimits: map[string]int{"Group": 9}},
+			series: limitTestSeries(3, 2, 0),
+			survivors: map[string][]string{
+				"test_bandwidth": {"g1", "g2"},
+				"test_state":     {"g1", "g2"},
+				"test_usage":     {"g1", "g1", "g2", "g2"},
+				"test_endpoint":  {"e1", "e2"},
+			},
+		},
+		{
+			name:   "target limit is shared between collectors",
+			target: config.Target{SeriesLimit: 12},
+			series: limitTestSeries(5, 5, 5),
+			survivors: map[string][]string{
+				"test_bandwidth": {"g1"},
+				"test_state":     {"g1"},
+				"test_usage":     {"g1", "g1"},
+				"test_endpoint":  {"e1", "e2", "e3", "e4"},
+				"test_server":    {"s1", "s2", "s3", "s4"},
+			},
+		},
+		{
+			name:   "collectors within an even share leave the rest to others",
+			target: config.Target{SeriesLimit: 12},
+			series: limitTestSeries(5, 1, 1),
+			survivors: map[string][]string{
+				"test_bandwidth": {"g1", "g2"},
+				"test_state":     {"g1", "g2"},
+				"test_usage":     {"g1", "g1", "g2", "g2"},
+				"test_endpoint":  {"e1"},
+				"test_server":    {"s1"},
+			},
+		},
+		{
+			name:   "records larger than the remaining share are dropped",
+			target: config.Target{SeriesLimit: 7},
+			series: limitTestSeries(5, 5, 5),
+			survivors: map[string][]string{
+				"test_endpoint": {"e1", "e2"},
+				"test_server":   {"s1", "s2"},
+			},
+		},
+		{
+			name:   "unused shares go to later collectors",
+			target: config.Target{SeriesLimit: 9},
+			series: limitTestSeries(5, 1, 1),
+			survivors: map[string][]string{
+				"test_bandwidth": {"g1"},
+				"test_state":     {"g1"},
+				"test_usage":     {"g1", "g1"},
+				"test_endpoint":  {"e1"},
+				"test_server":    {"s1"},
+			},
+		},
+		{
+			name:   "budget smaller than the collectors",
+			target: config.Target{SeriesLimit: 2},
+			series: limitTestSeries(5, 5, 5),
+			survivors: map[string][]string{
+				"test_server": {"s1"},
+			},
+		},
+		{
+			name:   "collector limits apply before the target limit",
+			target: config.Target{SeriesLimit: 12, CollectorSeriesLimits: map[string]int{"Endpoint": 1}},
+			series: limitTestSeries(5, 5, 5),
+			survivors: map[string][]string{
+				"test_bandwidth": {"g1"},
+				"test_state":     {"g1"},
+				"test_usage":     {"g1", "g1"},
+				"test_endpoint":  {"e1"},
+				"test_server":    {"s1", "s2", "s3", "s4", "s5"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Series of collectors without a limit are sent straight on, as collect does
+			ch := make(chan prometheus.Metric, len(tt.series))
+			l := newSeriesLimiter(tt.target, limitTestMetrics)
+			for _, m := range tt.series {
+				if !l.hold(m) {
+					ch <- m
+				}
+			}
+			l.flush(ch)
+			close(ch)
+
+			survivors := make(map[string][]string)
+			for m := range ch {
+				var d dto.Metric
+				if err := m.Write(&d); err != nil {
+					t.Fatal(err)
+				}
+				for _, lp := range d.Label {
+					switch lp.GetName() {
+					case "name", "address", "server":
+						survivors[descName(m.Desc())] = append(survivors[descName(m.Desc())], lp.GetValue())
+					}
+				}
+			}
+			for _, values := range survivors {
+				sort.Strings(values)
+			}
+
+			if !reflect.DeepEqual(survivors, tt.survivors) {
+				t.Errorf("surviving series = %v, want %v", survivors, tt.survivors)
+			}
+		})
+	}
+}
+
+// descName names the test descriptors, which do not expose their names
+func descName(d *prometheus.Desc) string {
+	switch d {
+	case limitTestBandwidth:
+		return "test_bandwidth"
+	case limitTestState:
+		return "test_state"
+	case limitTestUsage:
+		return "test_usage"
+	case limitTestEndpoint:
+		return "test_endpoint"
+	}
+	return "test_server"
+}
@@ -0,0 +1,208 @@
+package exporter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// pollers holds the running background pollers, keyed by target name
+	pollers      = make(map[string]*poller)
+	pollersMutex sync.RWMutex
+)
+
+var snapshotMetrics = map[string]*prometheus.Desc{
+	"Snapshot_Age": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "exporter", "snapshot_age_seconds"),
+		"Time since the metrics served for this target were collected, in seconds",
+		nil, nil,
+	),
+	"Snapshot_Duration": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "exporter", "snapshot_duration_seconds"),
+		"How long the background collection of the served snapshot took, in seconds",
+		nil, nil,
+	),
+}
+
+// poller periodically collects a target in the background, keeping the latest results as a snapshot
+type poller struct {
+	target   config.Target
+	metrics  []lib.SonusMetric
+	stop     chan struct{}
+	snapshot atomic.Pointer[snapshot]
+}
+
+// snapshot is an immutable set of metrics collected from a target
+type snapshot struct {
+	metrics     []prometheus.Metric
+	collectedAt time.Time
+	duration    time.Duration
+}
+
+// SyncPollers starts a background poller for each target with a poll interval, stopping pollers
+// for targets that were removed or changed since the last call. API URL health tracking of removed
+// targets is stopped as well.
+func SyncPollers(metrics []lib.SonusMetric, c config.Config) {
+	pollersMutex.Lock()
+	defer pollersMutex.Unlock()
+
+	pruneHealthTrackers(c)
+	pruneTopologyCaches(c)
+	pruneBreakers(c)
+	pruneSessions(c)
+
+	wanted := make(map[string]config.Target)
+	for _, t := range c.Targets {
+		if t.PollInterval > 0 {
+			wanted[t.Name] = t
+		}
+	}
+
+	for name, p := range pollers {
+		if t, ok := wanted[name]; !ok || !reflect.DeepEqual(t, p.target) {
+			log.Infof("Stopping background polling of target %q", name)
+			close(p.stop)
+			delete(pollers, name)
+		}
+	}
+
+	for name, t := range wanted {
+		if _, ok := pollers[name]; ok {
+			continue
+		}
+
+		p := &poller{
+			target:  t,
+			metrics: enabledMetrics(metrics, t, nil),
+			stop:    make(chan struct{}),
+		}
+		pollers[name] = p
+
+		log.Infof("Polling target %q every %v", name, t.PollInterval)
+		go p.run()
+	}
+}
+
+// pollerFor returns the running poller for the target, or nil if it is not polled in the background
+func pollerFor(target config.Target) *poller {
+	pollersMutex.RLock()
+	defer pollersMutex.RUnlock()
+
+	p, ok := pollers[target.Name]
+	if !ok || !reflect.DeepEqual(target, p.target) {
+		return nil
+	}
+	return p
+}
+
+func (p *poller) run() {
+	ticker := time.NewTicker(p.target.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.poll()
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll collects the target and replaces the snapshot with the results
+func (p *poller) poll() {
+	var (
+		collected []prometheus.Metric
+		ch        = make(chan prometheus.Metric)
+		done      = make(chan struct{})
+		ex        = &Exporter{Metrics: p.metrics, Target: p.target}
+		start     = time.Now()
+	)
+
+	go func() {
+		for m := range ch {
+			collected = append(collected, m)
+		}
+		close(done)
+	}()
+
+	// A poll may take at most one interval, so polls never overlap
+	ctx, cancel := context.WithTimeout(context.Background(), p.target.PollInterval)
+	defer cancel()
+
+	ex.collect(ctx, ch)
+	close(ch)
+	<-done
+
+	p.snapshot.Store(&snapshot{
+		metrics:     collected,
+		collectedAt: time.Now(),
+		duration:    time.Since(start),
+	})
+	log.Infof("Background poll of target %q collected %d metrics", p.target.Name, len(collected))
+}
+
+// labelValue returns the value of the named label of the metric, or an empty string when it has none
+func labelValue(m prometheus.Metric, name string) string {
+	var d dto.Metric
+	if err := m.Write(&d); err != nil {
+		return ""
+	}
+	for _, lp := range d.Label {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
+
+// replay sends the metrics of the latest snapshot described by the exporter, along with its age.
+// Until the first poll finishes, the target is reported as down.
+func (p *poller) replay(e *Exporter, ch chan<- prometheus.Metric) {
+	s := p.snapshot.Load()
+	if s == nil {
+		log.Warnf("No snapshot collected yet for target %q", p.target.Name)
+		ch <- prometheus.MustNewConstMetric(scrapeMetrics["Up"], prometheus.GaugeValue, 0, e.Name)
+		return
+	}
+
+	described := make(map[*prometheus.Desc]bool)
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		e.Describe(descs)
+		close(descs)
+	}()
+	for d := range descs {
+		described[d] = true
+	}
+
+	// Collector success shares one descriptor between collectors, so it is filtered by its collector label
+	requested := make(map[string]bool)
+	for _, name := range e.enabledCollectors() {
+		requested[name] = true
+	}
+
+	for _, m := range s.metrics {
+		if !described[m.Desc()] {
+			continue
+		}
+		if m.Desc() == scrapeMetrics["Scrape_Collector_Success"] && !requested[labelValue(m, "collector")] {
+			continue
+		}
+		ch <- m
+	}
+
+	ch <- prometheus.MustNewConstMetric(snapshotMetrics["Snapshot_Age"], prometheus.GaugeValue, time.Since(s.collectedAt).Seconds())
+	ch <- prometheus.MustNewConstMetric(snapshotMetrics["Snapshot_Duration"], prometheus.GaugeValue, s.duration.Seconds())
+}
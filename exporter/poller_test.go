@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPollerReplay(t *testing.T) {
+	var (
+		groupDesc    = prometheus.NewDesc("test_group", "", []string{"name"}, nil)
+		endpointDesc = prometheus.NewDesc("test_endpoint", "", []string{"address"}, nil)
+		metrics      = []lib.SonusMetric{
+			{Name: "Group", APIMetrics: map[string]*prometheus.Desc{"Group": groupDesc}},
+			{Name: "Endpoint", APIMetrics: map[string]*prometheus.Desc{"Endpoint": endpointDesc}},
+		}
+		target = config.Target{Name: "sbc", PollInterval: time.Minute}
+	)
+
+	success := func(collector string) prometheus.Metric {
+		return prometheus.MustNewConstMetric(scrapeMetrics["Scrape_Collector_Success"], prometheus.GaugeValue, 1, collector)
+	}
+
+	p := &poller{target: target, metrics: metrics}
+	p.snapshot.Store(&snapshot{
+		metrics: []prometheus.Metric{
+			prometheus.MustNewConstMetric(scrapeMetrics["Up"], prometheus.GaugeValue, 1, "sbc"),
+			prometheus.MustNewConstMetric(groupDesc, prometheus.GaugeValue, 1, "g1"),
+			prometheus.MustNewConstMetric(endpointDesc, prometheus.GaugeValue, 1, "e1"),
+			success(serverStatusCollector),
+			success(zoneStatusCollector),
+			success("Group"),
+			success("Endpoint"),
+		},
+		collectedAt: time.Now(),
+	})
+
+	tests := []struct {
+		name    string
+		collect []string
+		want    []string
+	}{
+		{"everything", nil, []string{"sonus_up", "test_group", "test_endpoint", "success ServerStatus", "success ZoneStatus", "success Group", "success Endpoint"}},
+		{"one collector", []string{"Group"}, []string{"sonus_up", "test_group", "success Group"}},
+		{"built in collector", []string{"ServerStatus"}, []string{"sonus_up", "success ServerStatus"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Exporter{Metrics: enabledMetrics(metrics, target, tt.collect), Target: target, requested: tt.collect}
+
+			ch := make(chan prometheus.Metric, 20)
+			p.replay(e, ch)
+			close(ch)
+
+			var got []string
+			for m := range ch {
+				switch m.Desc() {
+				case scrapeMetrics["Up"]:
+					got = append(got, "sonus_up")
+				case groupDesc:
+					got = append(got, "test_group")
+				case endpointDesc:
+					got = append(got, "test_endpoint")
+				case scrapeMetrics["Scrape_Collector_Success"]:
+					got = append(got, "success "+labelValue(m, "collector"))
+				}
+			}
+
+			sort.Strings(got)
+			want := append([]string{}, tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPollerReplayWithoutSnapshot(t *testing.T) {
+	target := config.Target{Name: "sbc", PollInterval: time.Minute}
+	p := &poller{target: target}
+
+	ch := make(chan prometheus.Metric, 1)
+	p.replay(&Exporter{Target: target}, ch)
+	close(ch)
+
+	m := <-ch
+	if m == nil || m.Desc() != scrapeMetrics["Up"] || labelValue(m, "target") != "sbc" {
+		t.Fatalf("replay without a snapshot sent %v, want sonus_up for the target", m)
+	}
+}
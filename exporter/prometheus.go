@@ -1,8 +1,9 @@
 package exporter
 
 import (
+	"context"
 	"crypto/tls"
-	"net/http"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -14,8 +15,6 @@ import (
 )
 
 var (
-	httpTransport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
-
 	// metricDisposition is a counter metric that tracks success per each metric type
 	metricDisposition = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "sonus",
@@ -34,26 +33,81 @@ var (
 	}, []string{"name", "stage"})
 )
 
+var scrapeMetrics = map[string]*prometheus.Desc{
+	"Up": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "", "up"),
+		"Whether an active SBC was found in the target's API URLs. 1 = up",
+		[]string{"target"}, nil,
+	),
+	"Scrape_Duration": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "scrape", "duration_seconds"),
+		"How long the collection of the target took, in seconds",
+		nil, nil,
+	),
+	"Scrape_Collector_Success": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "scrape", "collector_success"),
+		"Whether all requests of a collector succeeded. 1 = success",
+		[]string{"collector"}, nil,
+	),
+	"API_Certificate_Expiry": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "api", "certificate_expiry_timestamp_seconds"),
+		"When the earliest expiring certificate presented by the API URL expires, as a unix timestamp",
+		[]string{"url"}, nil,
+	),
+	"Discovered": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "", "discovered"),
+		"Number of address contexts, zones and ipInterfaceGroups found on the SBC in this scrape",
+		[]string{"type"}, nil,
+	),
+	"Active_API_URL": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "", "active_api_url"),
+		"Which of the target's API URLs is serving requests. 1 = active",
+		[]string{"url"}, nil,
+	),
+}
+
 func init() {
 	prometheus.MustRegister(metricDisposition)
 	prometheus.MustRegister(metricDuration)
 }
 
-// Exporter is used to store Metrics data and embeds the config struct.
+// Exporter is used to store Metrics data and embeds the target config struct.
 // This is done so that the relevant functions have easy access to the
 // user defined runtime configuration when the Collect method is called.
 type Exporter struct {
 	Metrics []lib.SonusMetric
-	config.Config
+	config.Target
+
+	// requested restricts the scrape to the named collectors, when not empty
+	requested []string
+
+	// poller holds the background snapshots for the target, if it is polled
+	poller *poller
+
+	// scrapeCtx is cancelled when the scrape runs out of time
+	scrapeCtx context.Context
 }
 
 // Describe - loops through the API metrics and passes them to prometheus.Describe
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-	for _, sm := range serverStatusMetrics {
+	if e.collectorEnabled(serverStatusCollector) {
+		for _, sm := range serverStatusMetrics {
+			ch <- sm
+		}
+	}
+	if e.collectorEnabled(zoneStatusCollector) {
+		for _, zm := range zoneStatusMetrics {
+			ch <- zm
+		}
+	}
+	for _, pm := range snapshotMetrics {
+		ch <- pm
+	}
+	for _, sm := range scrapeMetrics {
 		ch <- sm
 	}
-	for _, zm := range zoneStatusMetrics {
-		ch <- zm
+	for _, hm := range healthMetrics {
+		ch <- hm
 	}
 
 	for _, m := range e.Metrics {
@@ -66,31 +120,78 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 // Collect function, called on by Prometheus Client library
 // This function is called when a scrape is performed on the /metrics page
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	if e.poller != nil {
+		e.poller.replay(e, ch)
+		return
+	}
+
+	ctx := e.scrapeCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	e.collect(ctx, ch)
+}
+
+// collect queries the SBC API and processes the responses into metrics.
+// When ctx is done before every request has finished, the metrics collected so far are returned.
+func (e *Exporter) collect(ctx context.Context, ch chan<- prometheus.Metric) {
 
 	var (
 		addressContexts           []*addressContext
 		apiBase                   string
 		collectCount, resultCount uint
-		httpClient                = &http.Client{Transport: httpTransport, Timeout: e.APITimeout}
 		results                   = make(chan lib.MetricResult)
+		metrics                   = make(chan prometheus.Metric)
+		pending                   = make(map[string]int)
 		serverStatusBody          *[]byte
+		collectorSuccess          = make(map[string]bool)
+		start                     = time.Now()
 	)
 
-	for i, url := range e.APIURLs {
+	for _, name := range e.enabledCollectors() {
+		collectorSuccess[name] = false
+	}
+
+	health := healthFor(e.Target)
+
+	// Summary metrics are emitted however far the scrape got, so an unreachable SBC can be told
+	// apart from the exporter being down
+	defer func() {
+		e.collectScrapeSummary(ch, apiBase, collectorSuccess, time.Since(start))
+		health.collect(ch)
+	}()
+
+	api, err := newAPIClient(e.Target)
+	if err != nil {
+		log.Errorf("Unable to create HTTP client for target %q: %v", e.Name, err)
+		return
+	}
+	defer api.client.CloseIdleConnections()
+
+	for _, url := range health.candidates() {
 		serverStatusUrl := getServerStatusURL(url)
-		response, err := doHTTPRequest(httpClient, serverStatusUrl, e.APIUser, e.APIPass)
+		response, err := doHTTPRequestOnce(ctx, api, serverStatusUrl)
 
+		if err != nil && ctx.Err() != nil {
+			log.Errorf("Scrape of target %q ran out of time validating API_URL %q", e.Name, url)
+			break
+		}
 		if err != nil {
-			log.Errorf("Error encountered attemping to validate API_URL %q, index %d. Error: %v", url, i, err)
+			log.Errorf("Error encountered attemping to validate API_URL %q. Error: %v", url, err)
+			health.fail(url, err)
 			continue
 		}
 		if response.response.StatusCode == 200 {
 			apiBase = url
 			log.Infof("Using API_URL %q.", apiBase)
 			serverStatusBody = response.body
+			health.succeeded(url)
+			collectCertificateExpiry(ch, url, response.response.TLS)
 			break
 		} else {
 			log.Errorf("Non-200 HTTP reponse (%d) validating API_URL %q.", response.response.StatusCode, url)
+			health.fail(url, fmt.Errorf("non-200 HTTP response (%d)", response.response.StatusCode))
 		}
 
 	}
@@ -100,110 +201,259 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 
-	serverStatusErr := processServerStatus(serverStatusBody, ch)
-	if serverStatusErr != nil {
-		log.Errorf("Error while processing serverStatus: %v", serverStatusErr)
+	if e.collectorEnabled(serverStatusCollector) {
+		serverStatusErr := processServerStatus(serverStatusBody, ch)
+		if serverStatusErr != nil {
+			log.Errorf("Error while processing serverStatus: %v", serverStatusErr)
+		}
+		collectorSuccess[serverStatusCollector] = serverStatusErr == nil
 	}
 
-	// Create addressContext structs, and identify zones and ipInterfaceGroups
-	for _, acName := range e.Config.APIAddressContexts {
-		var (
-			ac       = addressContext{Name: acName}
-			err      error
-			response *httpResponse
-		)
+	// Only look up zones and ipInterfaceGroups when something needs them
+	needZones := e.collectorEnabled(zoneStatusCollector) || e.hasRepetition(lib.RepeatPerAddressContextZone)
+	needIPInterfaceGroups := e.hasRepetition(lib.RepeatPerAddressContextIpInterfaceGroup)
 
-		addressContexts = append(addressContexts, &ac)
+	topo, cached, err := topologyFor(e.Target).get(ctx, api, apiBase, needZones, needIPInterfaceGroups)
+	if err != nil {
+		return
+	}
+	zoneErr := topo.zoneErr
 
-		zoneStatusUrl := ac.getZoneStatusURL(lib.MetricContext{APIBase: apiBase, AddressContext: ac.Name})
-		response, err = doHTTPRequest(httpClient, zoneStatusUrl, e.APIUser, e.APIPass)
-		if err != nil {
-			log.Errorf("Unable to perform HTTP request to %q. Error: %v", zoneStatusUrl, err)
-			return
+	for _, ac := range topo.addressContexts {
+		if !e.collectorEnabled(zoneStatusCollector) {
+			addressContexts = append(addressContexts, ac)
+			continue
 		}
-		if response.response.StatusCode != 200 {
-			log.Errorf("Non-200 HTTP reponse (%d) to %q.", response.response.StatusCode, zoneStatusUrl)
-			return
+
+		// Zone usage changes between scrapes, so a cached topology has its zones looked up again
+		if cached {
+			fresh := &addressContext{Name: ac.Name, IPInterfaceGroups: ac.IPInterfaceGroups}
+			if err := fresh.fetchZones(ctx, api, apiBase); err != nil {
+				zoneErr = err
+				addressContexts = append(addressContexts, ac)
+				continue
+			}
+			ac = fresh
 		}
-		err = processZones(&ac, response.body, ch)
 
-		ipInterfaceGroupUrl := ac.getIPInterfaceGroupURL(lib.MetricContext{APIBase: apiBase, AddressContext: ac.Name})
-		response, err = doHTTPRequest(httpClient, ipInterfaceGroupUrl, e.APIUser, e.APIPass)
-		if err != nil {
-			log.Errorf("Unable to perform HTTP request to %q. Error: %v", ipInterfaceGroupUrl, err)
-			return
+		collectZoneMetrics(lib.MetricContext{Filters: e.Filters}, ac, ch)
+		addressContexts = append(addressContexts, ac)
+	}
+
+	if e.collectorEnabled(zoneStatusCollector) {
+		collectorSuccess[zoneStatusCollector] = zoneErr == nil
+	}
+
+	e.collectDiscovered(ch, addressContexts, needZones, needIPInterfaceGroups)
+
+	// Collectors are successful unless one of their requests reports a failure
+	for _, m := range e.Metrics {
+		collectorSuccess[m.Name] = true
+	}
+
+	// Metrics are sent on by this goroutine rather than by the processors, so nothing is sent on ch after
+	// collect returns
+	jobs := e.expandJobs(lib.MetricContext{Context: ctx, APIBase: apiBase, Filters: e.Filters, Compatibility: e.Compatibility, MetricChannel: metrics, ResultChannel: results}, addressContexts)
+	collectCount = uint(len(jobs))
+
+	// Collectors with a timeout share a deadline across all of their requests
+	collectorCtxs := make(map[string]context.Context)
+	for i, job := range jobs {
+		pending[job.metric.Name]++
+
+		timeout := e.CollectorTimeout(job.metric.Name)
+		if timeout == 0 {
+			continue
 		}
-		if response.response.StatusCode != 200 {
-			log.Errorf("Non-200 HTTP reponse (%d) to %q.", response.response.StatusCode, ipInterfaceGroupUrl)
-			return
+		if _, ok := collectorCtxs[job.metric.Name]; !ok {
+			collectorCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			collectorCtxs[job.metric.Name] = collectorCtx
 		}
-		err = processIPInterfaceGroups(&ac, response.body)
+		jobs[i].ctx.Context = collectorCtxs[job.metric.Name]
 	}
 
-	// Perform HTTP requests one at a time then delegate xml deserialization and metric processing to a goroutine
-	go func() {
-		for _, metric := range e.Metrics {
-			ctx := lib.MetricContext{APIBase: apiBase, MetricChannel: ch, ResultChannel: results}
-			if metric.Repetition == lib.RepeatNone {
-				collectCount++
-				doHTTPAndProcess(e, metric, ctx, httpClient)
-			} else if metric.Repetition == lib.RepeatPerAddressContext {
-				for _, ac := range addressContexts {
-					ctx.AddressContext = ac.Name
-
-					collectCount++
-					doHTTPAndProcess(e, metric, ctx, httpClient)
-				}
-			} else if metric.Repetition == lib.RepeatPerAddressContextZone {
-				for _, ac := range addressContexts {
-					for _, zone := range ac.Zones {
-						ctx.AddressContext = ac.Name
-						ctx.Zone = zone.Name
-
-						collectCount++
-						doHTTPAndProcess(e, metric, ctx, httpClient)
-					}
-				}
-			} else if metric.Repetition == lib.RepeatPerAddressContextIpInterfaceGroup {
-				for _, ac := range addressContexts {
-					for _, ipig := range ac.IPInterfaceGroups {
-						ctx.AddressContext = ac.Name
-						ctx.IPInterfaceGroup = ipig.Name
-
-						collectCount++
-						doHTTPAndProcess(e, metric, ctx, httpClient)
-					}
-				}
+	if collectCount == 0 {
+		log.Info("No collectors to run")
+		return
+	}
+
+	// Perform HTTP requests across a bounded pool of workers, then delegate xml deserialization
+	// and metric processing to a goroutine
+	jobChannel := make(chan collectJob)
+	for i := 0; i < e.Concurrency; i++ {
+		go func() {
+			for job := range jobChannel {
+				doHTTPAndProcess(job.metric, job.ctx, api)
 			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			jobChannel <- job
 		}
+		close(jobChannel)
 	}()
 
-	for {
+	// Held back series are emitted once the collectors are done, or the scrape runs out of time
+	limiter := newSeriesLimiter(e.Target, e.Metrics)
+	defer limiter.flush(ch)
+
+	for resultCount < collectCount {
 		select {
+		case m := <-metrics:
+			if !limiter.hold(m) {
+				ch <- m
+			}
 		case result := <-results:
 			var successString = strconv.FormatBool(result.Success)
 			metricDisposition.WithLabelValues(result.Name, successString).Inc()
 
+			if !result.Success {
+				collectorSuccess[result.Name] = false
+			}
+
+			pending[result.Name]--
 			resultCount++
-			if resultCount == collectCount {
-				log.Info("Done collectin'")
-				httpTransport.CloseIdleConnections()
-				return
+		case <-ctx.Done():
+			log.Warnf("Scrape of target %q ran out of time with %d of %d requests outstanding, returning partial results",
+				e.Name, collectCount-resultCount, collectCount)
+			for name, n := range pending {
+				if n > 0 {
+					collectorSuccess[name] = false
+				}
+			}
+			go discardResults(metrics, results, collectCount-resultCount)
+			return
+		}
+	}
+
+	log.Info("Done collectin'")
+}
+
+// discardResults receives and drops the output of requests still running when a scrape ran out of time,
+// so their processors are not left blocked
+func discardResults(metrics <-chan prometheus.Metric, results <-chan lib.MetricResult, outstanding uint) {
+	for outstanding > 0 {
+		select {
+		case <-metrics:
+		case <-results:
+			outstanding--
+		}
+	}
+}
+
+// collectScrapeSummary emits whether the target was reachable, which API URL served the scrape,
+// how long it took and which collectors succeeded
+func (e *Exporter) collectScrapeSummary(ch chan<- prometheus.Metric, apiBase string, collectorSuccess map[string]bool, duration time.Duration) {
+	ch <- prometheus.MustNewConstMetric(scrapeMetrics["Up"], prometheus.GaugeValue, lib.BoolToFloat(apiBase != ""), e.Name)
+	ch <- prometheus.MustNewConstMetric(scrapeMetrics["Scrape_Duration"], prometheus.GaugeValue, duration.Seconds())
+
+	for _, url := range e.APIURLs {
+		ch <- prometheus.MustNewConstMetric(scrapeMetrics["Active_API_URL"], prometheus.GaugeValue, lib.BoolToFloat(url == apiBase), url)
+	}
+
+	for name, success := range collectorSuccess {
+		ch <- prometheus.MustNewConstMetric(scrapeMetrics["Scrape_Collector_Success"], prometheus.GaugeValue, lib.BoolToFloat(success), name)
+	}
+}
+
+// collectDiscovered emits how many address contexts, and of the zones and ipInterfaceGroups looked up, were found
+func (e *Exporter) collectDiscovered(ch chan<- prometheus.Metric, addressContexts []*addressContext, zones, ipInterfaceGroups bool) {
+	var zoneCount, ipInterfaceGroupCount int
+
+	for _, ac := range addressContexts {
+		zoneCount += len(ac.Zones)
+		ipInterfaceGroupCount += len(ac.IPInterfaceGroups)
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeMetrics["Discovered"], prometheus.GaugeValue, float64(len(addressContexts)), "address_context")
+	if zones {
+		ch <- prometheus.MustNewConstMetric(scrapeMetrics["Discovered"], prometheus.GaugeValue, float64(zoneCount), "zone")
+	}
+	if ipInterfaceGroups {
+		ch <- prometheus.MustNewConstMetric(scrapeMetrics["Discovered"], prometheus.GaugeValue, float64(ipInterfaceGroupCount), "ip_interface_group")
+	}
+}
+
+// collectCertificateExpiry emits when the earliest expiring certificate presented by the API URL expires
+func collectCertificateExpiry(ch chan<- prometheus.Metric, url string, state *tls.ConnectionState) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	expiry := state.PeerCertificates[0].NotAfter
+	for _, cert := range state.PeerCertificates[1:] {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeMetrics["API_Certificate_Expiry"], prometheus.GaugeValue, float64(expiry.Unix()), url)
+}
+
+// collectJob is a single HTTP request and processing run of a metric
+type collectJob struct {
+	metric lib.SonusMetric
+	ctx    lib.MetricContext
+}
+
+// expandJobs repeats each metric according to its Repetition, over the address contexts,
+// zones and ipInterfaceGroups discovered for this scrape
+func (e *Exporter) expandJobs(ctx lib.MetricContext, addressContexts []*addressContext) []collectJob {
+	var jobs []collectJob
+
+	for _, metric := range e.Metrics {
+		if metric.Repetition == lib.RepeatNone {
+			jobs = append(jobs, collectJob{metric, ctx})
+		} else if metric.Repetition == lib.RepeatPerAddressContext {
+			for _, ac := range addressContexts {
+				c := ctx
+				c.AddressContext = ac.Name
+
+				jobs = append(jobs, collectJob{metric, c})
+			}
+		} else if metric.Repetition == lib.RepeatPerAddressContextZone {
+			for _, ac := range addressContexts {
+				for _, zone := range ac.Zones {
+					// Excluded zones are not requested at all
+					if ctx.Skip(metric.Name, lib.FilterZone, zone.Name) {
+						continue
+					}
+
+					c := ctx
+					c.AddressContext = ac.Name
+					c.Zone = zone.Name
+
+					jobs = append(jobs, collectJob{metric, c})
+				}
+			}
+		} else if metric.Repetition == lib.RepeatPerAddressContextIpInterfaceGroup {
+			for _, ac := range addressContexts {
+				for _, ipig := range ac.IPInterfaceGroups {
+					c := ctx
+					c.AddressContext = ac.Name
+					c.IPInterfaceGroup = ipig.Name
+
+					jobs = append(jobs, collectJob{metric, c})
+				}
 			}
 		}
 	}
 
+	return jobs
 }
 
-func doHTTPAndProcess(e *Exporter, metric lib.SonusMetric, ctx lib.MetricContext, httpClient *http.Client) {
+func doHTTPAndProcess(metric lib.SonusMetric, ctx lib.MetricContext, api *apiClient) {
 	url := metric.URLGetter(ctx)
 	ht := time.Now()
-	response, err := doHTTPRequest(httpClient, url, e.APIUser, e.APIPass)
+	response, err := doHTTPRequest(ctx.Context, api, url)
 	metricDuration.WithLabelValues(metric.Name, "http").Observe(time.Since(ht).Seconds())
 
 	if err != nil {
 		log.Errorf("Unable to perform HTTP request to %q. Error: %v", url, err)
-		ctx.ResultChannel <- lib.MetricResult{Success: false, Errors: []*error{&err}}
+		ctx.ResultChannel <- lib.MetricResult{Name: metric.Name, Success: false, Errors: []*error{&err}}
 	} else {
 		go func(m lib.SonusMetric, c lib.MetricContext, r *httpResponse) {
 			mt := time.Now()
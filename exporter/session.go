@@ -0,0 +1,155 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+
+	"sonus-metrics-exporter/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// sessions holds the REST sessions established with each target, keyed by target name
+	sessions      = make(map[string]*session)
+	sessionsMutex sync.Mutex
+
+	// loginAttempts is a counter metric that tracks how often a session is established with each target
+	loginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "login_attempts_total",
+		Help:      "Number of times a REST session login was attempted",
+	}, []string{"target"})
+
+	// loginFailures is a counter metric that tracks failed session logins for each target
+	loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "login_failures_total",
+		Help:      "Number of times a REST session login failed",
+	}, []string{"target"})
+)
+
+func init() {
+	prometheus.MustRegister(loginAttempts)
+	prometheus.MustRegister(loginFailures)
+}
+
+// session holds the cookies the SBC issued to a target, so that credentials are only sent when logging in,
+// rather than creating an authentication log entry and CLI session on every request
+type session struct {
+	jar http.CookieJar
+	key string
+
+	// loginMutex ensures only one request logs in at a time
+	loginMutex sync.Mutex
+
+	mutex    sync.RWMutex
+	loggedIn time.Time
+	ttl      time.Duration
+}
+
+// sessionFor returns the session for the target, replacing it when the target's credentials or URLs change
+func sessionFor(t config.Target, password config.Secret) *session {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	key := fmt.Sprintf("%s\x00%s\x00%s", t.APIUser, string(password), strings.Join(t.APIURLs, " "))
+
+	s, ok := sessions[t.Name]
+	if !ok || s.key != key || s.ttl != t.Auth.SessionTTL {
+		jar, _ := cookiejar.New(nil)
+		s = &session{jar: jar, key: key, ttl: t.Auth.SessionTTL}
+		sessions[t.Name] = s
+	}
+
+	return s
+}
+
+// pruneSessions drops the sessions and login counts of targets which are no longer configured
+func pruneSessions(c config.Config) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	for name := range sessions {
+		if _, ok := c.Target(name); !ok {
+			delete(sessions, name)
+			loginAttempts.DeleteLabelValues(name)
+			loginFailures.DeleteLabelValues(name)
+		}
+	}
+}
+
+// valid reports whether the session is logged in and has not reached its configured lifetime
+func (s *session) valid() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.loggedIn.IsZero() {
+		return false
+	}
+	return s.ttl == 0 || time.Since(s.loggedIn) < s.ttl
+}
+
+func (s *session) setLoggedIn(t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.loggedIn = t
+}
+
+// do performs a request using the session cookie, logging in again if there is no session or it has expired
+func (s *session) do(ctx context.Context, api *apiClient, url string) (*http.Response, error) {
+	if s.valid() {
+		resp, err := api.do(ctx, url, false)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		log.Infof("REST session for target %q was rejected, logging in again", api.target.Name)
+		s.setLoggedIn(time.Time{})
+	}
+
+	return s.login(ctx, api, url)
+}
+
+// login performs the request with credentials, keeping the session cookie the SBC returns
+func (s *session) login(ctx context.Context, api *apiClient, url string) (*http.Response, error) {
+	s.loginMutex.Lock()
+	defer s.loginMutex.Unlock()
+
+	// Another request may have logged in while this one was waiting
+	if s.valid() {
+		return api.do(ctx, url, false)
+	}
+
+	loginAttempts.WithLabelValues(api.target.Name).Inc()
+
+	resp, err := api.do(ctx, url, true)
+	if err != nil {
+		loginFailures.WithLabelValues(api.target.Name).Inc()
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		log.Errorf("REST session login to target %q failed with HTTP status %d", api.target.Name, resp.StatusCode)
+		loginFailures.WithLabelValues(api.target.Name).Inc()
+		return resp, nil
+	}
+
+	if len(s.jar.Cookies(resp.Request.URL)) == 0 {
+		log.Warnf("Target %q did not return a session cookie, credentials will be sent with every request", api.target.Name)
+		return resp, nil
+	}
+
+	s.setLoggedIn(time.Now())
+	return resp, nil
+}
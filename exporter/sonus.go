@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"sonus-metrics-exporter/config"
 	"sonus-metrics-exporter/lib"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,6 +18,10 @@ func getServerStatusURL(apiBase string) string {
 	return fmt.Sprintf("%s/operational/system/serverStatus/", apiBase)
 }
 
+func getAddressContextsURL(apiBase string) string {
+	return fmt.Sprintf("%s/operational/addressContext/", apiBase)
+}
+
 func (a *addressContext) getZoneStatusURL(ctx lib.MetricContext) string {
 	return fmt.Sprintf("%s/operational/addressContext/%s/zoneStatus/", ctx.APIBase, a.Name)
 }
@@ -36,6 +41,16 @@ var serverStatusMetrics = map[string]*prometheus.Desc{
 		"Current synchronization status. 1 = syncCompleted",
 		[]string{"server", "status_name"}, nil,
 	),
+	"System_Redundancy_Role_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "system", "redundancy_role_info"),
+		"Redundancy role of server, one series per role. 1 = current role",
+		[]string{"server", "state"}, nil,
+	),
+	"System_Sync_Status_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "system", "sync_status_info"),
+		"Synchronization status of server, one series per status. 1 = current status",
+		[]string{"server", "state"}, nil,
+	),
 	"System_Uptime": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "system", "uptime"),
 		"Current uptime of server, in seconds",
@@ -54,6 +69,21 @@ var zoneStatusMetrics = map[string]*prometheus.Desc{
 		"Total call limit per zone",
 		[]string{"direction", "addresscontext", "zone"}, nil,
 	),
+	"Zone_Total_Calls_Available": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "zone", "total_calls_available"),
+		"Number of calls which can still be placed per zone",
+		[]string{"addresscontext", "zone"}, nil,
+	),
+	"Zone_Active_SIP_Registrations": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "zone", "active_sip_registrations"),
+		"Number of active SIP registrations per zone",
+		[]string{"addresscontext", "zone"}, nil,
+	),
+	"Zone_Utilization_Ratio": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "zone", "utilization_ratio"),
+		"Calls in use divided by the call limit per zone, for zones with a limit",
+		[]string{"addresscontext", "zone"}, nil,
+	),
 }
 
 type (
@@ -113,23 +143,10 @@ func (s serverStatus) parseUptime(upType serverUptimeType) float64 {
 	}
 }
 
-func (s serverStatus) mgmtRedunRoleToFloat() float64 {
-	switch s.ManagementRedundancyRole {
-	case "active":
-		return 1
-	default:
-		return 0
-	}
-}
-
-func (s serverStatus) syncStatusToFloat() float64 {
-	switch s.SyncStatus {
-	case "syncCompleted":
-		return 1
-	default:
-		return 0
-	}
-}
+var (
+	redundancyRoles = lib.NewEnum("redundancy_role", "active", "standby")
+	syncStatuses    = lib.NewEnum("sync_status", "syncCompleted", "syncInProgress", "unprotectedRunningStandby")
+)
 
 type addressContext struct {
 	Name              string
@@ -163,8 +180,10 @@ func processServerStatus(xmlBody *[]byte, ch chan<- prometheus.Metric) error {
 	}
 
 	for _, server := range serverStatuses.ServerStatus {
-		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Redundancy_Role"], prometheus.GaugeValue, server.mgmtRedunRoleToFloat(), server.Name, server.ManagementRedundancyRole)
-		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Sync_Status"], prometheus.GaugeValue, server.syncStatusToFloat(), server.Name, server.SyncStatus)
+		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Redundancy_Role"], prometheus.GaugeValue, lib.BoolToFloat(server.ManagementRedundancyRole == "active"), server.Name, server.ManagementRedundancyRole)
+		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Sync_Status"], prometheus.GaugeValue, lib.BoolToFloat(server.SyncStatus == "syncCompleted"), server.Name, server.SyncStatus)
+		redundancyRoles.Collect(ch, serverStatusMetrics["System_Redundancy_Role_Info"], server.ManagementRedundancyRole, server.Name)
+		syncStatuses.Collect(ch, serverStatusMetrics["System_Sync_Status_Info"], server.SyncStatus, server.Name)
 		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Uptime"], prometheus.CounterValue, server.parseUptime(serverOSUptime), server.Name, "os")
 		ch <- prometheus.MustNewConstMetric(serverStatusMetrics["System_Uptime"], prometheus.CounterValue, server.parseUptime(serverAppUptime), server.Name, "application")
 	}
@@ -172,20 +191,60 @@ func processServerStatus(xmlBody *[]byte, ch chan<- prometheus.Metric) error {
 	return nil
 }
 
-func processZones(addressContext *addressContext, xmlBody *[]byte, ch chan<- prometheus.Metric) error {
+// addressContextCollection lists the address contexts configured on the SBC
+type addressContextCollection struct {
+	AddressContexts []struct {
+		Name string `xml:"name"`
+	} `xml:"addressContext"`
+}
+
+// processAddressContexts returns the names of the listed address contexts which match the discovery filters
+func processAddressContexts(xmlBody *[]byte, discovery config.DiscoveryConfig) ([]string, error) {
+	var (
+		acs   = new(addressContextCollection)
+		names []string
+	)
+
+	err := xml.Unmarshal(*xmlBody, &acs)
+	if err != nil {
+		log.Errorf("Failed to deserialize addressContext XML: %v", err)
+		return nil, err
+	}
+
+	for _, ac := range acs.AddressContexts {
+		if discovery.Matches(ac.Name) {
+			names = append(names, ac.Name)
+		} else {
+			log.Debugf("Skipping discovered address context %q", ac.Name)
+		}
+	}
+	return names, nil
+}
+
+func processZones(addressContext *addressContext, xmlBody *[]byte) error {
 	err := xml.Unmarshal(*xmlBody, &addressContext)
 	if err != nil {
 		log.Errorf("Failed to deserialize zoneStatus XML: %v", err)
 		return err
 	}
+	return nil
+}
 
+func collectZoneMetrics(ctx lib.MetricContext, addressContext *addressContext, ch chan<- prometheus.Metric) {
 	for _, zone := range addressContext.Zones {
+		if ctx.Skip(zoneStatusCollector, lib.FilterZone, zone.Name) {
+			continue
+		}
 		ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Total_Calls_Configured"], prometheus.GaugeValue, zone.TotalCallsConfigured, addressContext.Name, zone.Name)
 		ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Usage_Total"], prometheus.GaugeValue, zone.InboundCallsUsage, "inbound", addressContext.Name, zone.Name)
 		ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Usage_Total"], prometheus.GaugeValue, zone.OutboundCallsUsage, "outbound", addressContext.Name, zone.Name)
+		ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Total_Calls_Available"], prometheus.GaugeValue, zone.TotalCallsAvailable, addressContext.Name, zone.Name)
+		ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Active_SIP_Registrations"], prometheus.GaugeValue, zone.ActiveSipRegCount, addressContext.Name, zone.Name)
+		if zone.TotalCallsConfigured > 0 {
+			ch <- prometheus.MustNewConstMetric(zoneStatusMetrics["Zone_Utilization_Ratio"], prometheus.GaugeValue, (zone.InboundCallsUsage+zone.OutboundCallsUsage)/zone.TotalCallsConfigured, addressContext.Name, zone.Name)
+		}
 	}
 	log.Info("Zone Status and Metrics collected")
-	return nil
 }
 
 func processIPInterfaceGroups(addressContext *addressContext, xmlBody *[]byte) error {
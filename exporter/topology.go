@@ -0,0 +1,200 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/lib"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// topologyCaches holds the topology discovered for each target, keyed by target name
+	topologyCaches      = make(map[string]*topologyCache)
+	topologyCachesMutex sync.Mutex
+)
+
+// topology is the address contexts of a target, along with the zones and ipInterfaceGroups found in them.
+// A topology is not modified once fetched, as it may be shared by concurrent scrapes.
+type topology struct {
+	addressContexts   []*addressContext
+	zones             bool
+	ipInterfaceGroups bool
+	zoneErr           error
+	fetchedAt         time.Time
+}
+
+// topologyCache keeps the topology of a target between scrapes, refreshing it in the background once it is
+// older than the target's topology_cache_ttl
+type topologyCache struct {
+	key string
+
+	mutex      sync.Mutex
+	current    *topology
+	refreshing bool
+}
+
+// topologyFor returns the topology cache of the target, replacing it when the target's URLs or address contexts change.
+// Ad-hoc targets get an empty cache, so their topology is fetched on every scrape.
+func topologyFor(t config.Target) *topologyCache {
+	if t.Adhoc {
+		return &topologyCache{}
+	}
+
+	topologyCachesMutex.Lock()
+	defer topologyCachesMutex.Unlock()
+
+	key := fmt.Sprint(t.APIURLs, t.APIAddressContexts, t.Discovery)
+
+	c, ok := topologyCaches[t.Name]
+	if !ok || c.key != key {
+		c = &topologyCache{key: key}
+		topologyCaches[t.Name] = c
+	}
+
+	return c
+}
+
+// pruneTopologyCaches drops the topology of targets which are no longer configured
+func pruneTopologyCaches(c config.Config) {
+	topologyCachesMutex.Lock()
+	defer topologyCachesMutex.Unlock()
+
+	for name := range topologyCaches {
+		if _, ok := c.Target(name); !ok {
+			delete(topologyCaches, name)
+		}
+	}
+}
+
+// get returns the cached topology when it has the zones and ipInterfaceGroups asked for, starting a background
+// refresh when it has expired. Otherwise the topology is fetched, and cached when it was fetched without errors.
+// cached reports whether the topology came from the cache. Nothing is cached when the target has no topology_cache_ttl.
+func (c *topologyCache) get(ctx context.Context, api *apiClient, apiBase string, zones, ipInterfaceGroups bool) (topo *topology, cached bool, err error) {
+	if api.target.TopologyCacheTTL == 0 {
+		topo, err = fetchTopology(ctx, api, apiBase, zones, ipInterfaceGroups)
+		return topo, false, err
+	}
+
+	c.mutex.Lock()
+	current := c.current
+	if current != nil && (current.zones || !zones) && (current.ipInterfaceGroups || !ipInterfaceGroups) {
+		if time.Since(current.fetchedAt) > api.target.TopologyCacheTTL && !c.refreshing {
+			c.refreshing = true
+			go c.refresh(api.target, apiBase, current.zones, current.ipInterfaceGroups)
+		}
+		c.mutex.Unlock()
+		return current, true, nil
+	}
+	c.mutex.Unlock()
+
+	// Keep looking up what the cached topology had, so scrapes needing less do not lose it
+	if current != nil {
+		zones = zones || current.zones
+		ipInterfaceGroups = ipInterfaceGroups || current.ipInterfaceGroups
+	}
+
+	topo, err = fetchTopology(ctx, api, apiBase, zones, ipInterfaceGroups)
+	if err == nil && topo.zoneErr == nil {
+		c.store(topo)
+	}
+	return topo, false, err
+}
+
+// refresh fetches the topology in the background, keeping the cached one when it fails
+func (c *topologyCache) refresh(t config.Target, apiBase string, zones, ipInterfaceGroups bool) {
+	defer func() {
+		c.mutex.Lock()
+		c.refreshing = false
+		c.mutex.Unlock()
+	}()
+
+	api, err := newAPIClient(t)
+	if err != nil {
+		log.Errorf("Unable to create HTTP client to refresh the topology of target %q: %v", t.Name, err)
+		return
+	}
+	defer api.client.CloseIdleConnections()
+
+	topo, err := fetchTopology(context.Background(), api, apiBase, zones, ipInterfaceGroups)
+	if err != nil || topo.zoneErr != nil {
+		log.Errorf("Unable to refresh the topology of target %q, keeping the cached topology", t.Name)
+		return
+	}
+
+	c.store(topo)
+	log.Infof("Refreshed the topology of target %q", t.Name)
+}
+
+func (c *topologyCache) store(topo *topology) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.current = topo
+}
+
+// fetchTopology lists the target's address contexts, and looks up their zones and ipInterfaceGroups when asked to
+func fetchTopology(ctx context.Context, api *apiClient, apiBase string, zones, ipInterfaceGroups bool) (*topology, error) {
+	topo := &topology{zones: zones, ipInterfaceGroups: ipInterfaceGroups, fetchedAt: time.Now()}
+
+	addressContextNames := api.target.APIAddressContexts
+	if api.target.Discovery.Enabled {
+		response, err := doChecked(ctx, api, getAddressContextsURL(apiBase))
+		if err != nil {
+			return nil, err
+		}
+		addressContextNames, err = processAddressContexts(response.body, api.target.Discovery)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, acName := range addressContextNames {
+		ac := &addressContext{Name: acName}
+		topo.addressContexts = append(topo.addressContexts, ac)
+
+		if zones {
+			if err := ac.fetchZones(ctx, api, apiBase); err != nil {
+				topo.zoneErr = err
+			}
+		}
+
+		if ipInterfaceGroups {
+			response, err := doChecked(ctx, api, ac.getIPInterfaceGroupURL(lib.MetricContext{APIBase: apiBase, AddressContext: ac.Name}))
+			if err != nil {
+				return nil, err
+			}
+			// A malformed list of ipInterfaceGroups leaves the address context without any, as it always has
+			_ = processIPInterfaceGroups(ac, response.body)
+		}
+	}
+
+	return topo, nil
+}
+
+// fetchZones looks up the zones of the address context, along with their usage
+func (a *addressContext) fetchZones(ctx context.Context, api *apiClient, apiBase string) error {
+	response, err := doChecked(ctx, api, a.getZoneStatusURL(lib.MetricContext{APIBase: apiBase, AddressContext: a.Name}))
+	if err != nil {
+		return err
+	}
+	return processZones(a, response.body)
+}
+
+// doChecked makes a request, treating any response other than 200 as an error
+func doChecked(ctx context.Context, api *apiClient, url string) (*httpResponse, error) {
+	response, err := doHTTPRequest(ctx, api, url)
+	if err != nil {
+		log.Errorf("Unable to perform HTTP request to %q. Error: %v", url, err)
+		return nil, err
+	}
+	if response.response.StatusCode != 200 {
+		log.Errorf("Non-200 HTTP reponse (%d) to %q.", response.response.StatusCode, url)
+		return nil, fmt.Errorf("non-200 HTTP response (%d)", response.response.StatusCode)
+	}
+	return response, nil
+}
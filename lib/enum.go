@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// unknownStates is a counter metric that tracks state values not known to their Enum
+var unknownStates = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sonus",
+	Subsystem: "exporter",
+	Name:      "unknown_state_total",
+	Help:      "Number of times a state field had a value not known to the exporter",
+}, []string{"enum", "state"})
+
+func init() {
+	prometheus.MustRegister(unknownStates)
+}
+
+// Enum is the set of known values of a state field, emitted in the style of an OpenMetrics StateSet:
+// one series per state, set to 1 for the current state and 0 for the others
+type Enum struct {
+	name   string
+	states []string
+}
+
+// NewEnum returns an Enum named for the unknown state counter, with its known states
+func NewEnum(name string, states ...string) Enum {
+	return Enum{name: name, states: states}
+}
+
+// Collect sends one series per known state to the channel, with the state appended to the label values.
+// A state which is not known is sent as well, so it is not lost, and is counted as unknown.
+func (e Enum) Collect(ch chan<- prometheus.Metric, desc *prometheus.Desc, state string, labelValues ...string) {
+	known := false
+
+	for _, s := range e.states {
+		value := 0.0
+		if s == state {
+			value = 1
+			known = true
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(labelValues, s)...)
+	}
+
+	if !known {
+		log.Warnf("Unknown %s state %q", e.name, state)
+		unknownStates.WithLabelValues(e.name, state).Inc()
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, append(labelValues, state)...)
+	}
+}
+
+// BoolToFloat converts a condition to a metric value of 1 or 0
+func BoolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package lib
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of record which can be filtered out by a target's filters
+const (
+	FilterZone           = "zone"
+	FilterTrunkGroup     = "trunk_group"
+	FilterIPInterface    = "ip_interface"
+	FilterSipArsEndpoint = "sipars_endpoint"
+)
+
+// RecordFilter decides which records collectors emit metrics for
+type RecordFilter interface {
+	Matches(kind, name string) bool
+}
+
+// filteredRecords is a counter metric that tracks records skipped by a target's filters
+var filteredRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sonus",
+	Subsystem: "exporter",
+	Name:      "filtered_records_total",
+	Help:      "Number of records not emitted as metrics because of the target's filters",
+}, []string{"collector", "kind"})
+
+func init() {
+	prometheus.MustRegister(filteredRecords)
+}
+
+// Skip reports whether the named record is filtered out, counting it against the collector when it is
+func (ctx MetricContext) Skip(collector, kind, name string) bool {
+	if ctx.Filters == nil || ctx.Filters.Matches(kind, name) {
+		return false
+	}
+
+	log.Debugf("Collector %q skipping %s %q", collector, kind, name)
+	filteredRecords.WithLabelValues(collector, kind).Inc()
+	return true
+}
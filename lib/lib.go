@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -11,20 +13,29 @@ type (
 	// SonusMetric describes a class of metric, and how to load and process its data
 	SonusMetric struct {
 		Name       string
+		Help       string
 		Processor  func(MetricContext, *[]byte)
 		URLGetter  func(MetricContext) string
 		APIMetrics map[string]*prometheus.Desc
 		Repetition
+
+		// DisabledByDefault collectors only run when named in a target's collectors or in collect[]
+		DisabledByDefault bool
 	}
 
 	// MetricContext contains information and channels used by metric collectors to do their thing
 	MetricContext struct {
+		// Context is cancelled when the scrape runs out of time
+		Context          context.Context
 		APIBase          string
 		AddressContext   string
 		Zone             string
 		IPInterfaceGroup string
-		MetricChannel    chan<- prometheus.Metric
-		ResultChannel    chan<- MetricResult
+		// Filters decides which records are emitted, all of them when nil
+		Filters       RecordFilter
+		Compatibility Compatibility
+		MetricChannel chan<- prometheus.Metric
+		ResultChannel chan<- MetricResult
 	}
 
 	// MetricResult indicates success or failure+errors from a metric collector
@@ -41,3 +52,9 @@ const (
 	RepeatPerAddressContextIpInterfaceGroup
 	RepeatPerAddressContextZone
 )
+
+// Compatibility holds options which bring back the previous form of metrics that have since changed
+type Compatibility struct {
+	// SIPRetransmissionMethods also reports SIP retransmissions as "(retrans)" methods of the requests sent
+	SIPRetransmissionMethods bool `yaml:"sip_retransmission_methods"`
+}
@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// registered holds the collectors added with Register
+	registered      []SonusMetric
+	registeredMutex sync.Mutex
+)
+
+// Register adds a collector to the set run by the exporter. Collectors call it from an init function
+// in the file that defines them, so adding an endpoint does not require changes elsewhere.
+func Register(metric SonusMetric) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	registered = append(registered, metric)
+}
+
+// Registered returns the registered collectors, sorted by name
+func Registered() []SonusMetric {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+
+	metrics := make([]SonusMetric, len(registered))
+	copy(metrics, registered)
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	return metrics
+}
+
+// SameCollector reports whether two collector names refer to the same collector. Case and spaces are ignored,
+// so names with spaces, such as "SIP ARS", can be given as "SipArs" in configuration and collect[].
+func SameCollector(a, b string) bool {
+	return collectorKey(a) == collectorKey(b)
+}
+
+func collectorKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+// descCollector describes a fixed set of descriptors, so they can be checked by a prometheus.Registry
+type descCollector []*prometheus.Desc
+
+func (d descCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range d {
+		ch <- desc
+	}
+}
+
+func (d descCollector) Collect(chan<- prometheus.Metric) {}
+
+// Validate checks that every collector is complete, that no two collectors share a name, and that no
+// descriptor is defined twice or with conflicting help or labels. The builtin descriptors are those the
+// exporter emits itself, which collectors must not redefine.
+func Validate(metrics []SonusMetric, builtin ...*prometheus.Desc) error {
+	names := make(map[string]bool)
+	registry := prometheus.NewRegistry()
+
+	if err := registry.Register(descCollector(builtin)); err != nil {
+		return fmt.Errorf("invalid builtin metrics: %v", err)
+	}
+
+	for _, m := range metrics {
+		if m.Name == "" {
+			return fmt.Errorf("collector without a name")
+		}
+		if names[collectorKey(m.Name)] {
+			return fmt.Errorf("collector %q is registered more than once", m.Name)
+		}
+		names[collectorKey(m.Name)] = true
+
+		if m.Processor == nil || m.URLGetter == nil {
+			return fmt.Errorf("collector %q needs both a Processor and a URLGetter", m.Name)
+		}
+
+		var descs descCollector
+		for _, d := range m.APIMetrics {
+			descs = append(descs, d)
+		}
+		if err := registry.Register(descs); err != nil {
+			return fmt.Errorf("collector %q: %v", m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// String describes how a metric is repeated
+func (r Repetition) String() string {
+	switch r {
+	case RepeatNone:
+		return "once"
+	case RepeatPerAddressContext:
+		return "per addressContext"
+	case RepeatPerAddressContextIpInterfaceGroup:
+		return "per addressContext and ipInterfaceGroup"
+	case RepeatPerAddressContextZone:
+		return "per addressContext and zone"
+	}
+	return fmt.Sprintf("Repetition(%d)", uint8(r))
+}
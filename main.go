@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"net/http"
+	"os"
 
 	"sonus-metrics-exporter/config"
 	"sonus-metrics-exporter/exporter"
@@ -10,8 +12,6 @@ import (
 
 	"github.com/fatih/structs"
 	"github.com/infinityworks/go-common/logger"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,15 +19,10 @@ var (
 	log            *logrus.Logger
 	applicationCfg config.Config
 
-	metricList = []lib.SonusMetric{
-		metrics.DSPMetric,
-		metrics.FanMetric,
-		metrics.IPInterfaceMetric,
-		metrics.PowerSupplyMetric,
-		metrics.SipStatisticMetric,
-		metrics.SipArsMetric,
-		metrics.TGMetric,
-	}
+	// metricList holds the collectors registered by the metrics package
+	metricList []lib.SonusMetric
+
+	listCollectors = flag.Bool("list-collectors", false, "List the available collectors and exit.")
 )
 
 func init() {
@@ -37,19 +32,44 @@ func init() {
 
 func main() {
 
+	// Custom collectors are only read at startup, as they change the metrics the exporter describes
+	if applicationCfg.CollectorsFile != "" {
+		if err := metrics.LoadCustomCollectors(applicationCfg.CollectorsFile); err != nil {
+			log.Fatalf("Unable to load custom collectors: %v", err)
+		}
+	}
+
+	metricList = lib.Registered()
+	if err := exporter.ValidateMetrics(metricList); err != nil {
+		log.Fatal(err)
+	}
+
+	if *listCollectors {
+		if err := exporter.ListCollectors(os.Stdout, metricList); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	log.WithFields(structs.Map(applicationCfg)).Info("Starting Exporter")
 
-	ex := exporter.Exporter{
-		Metrics: metricList,
-		Config:  applicationCfg,
+	if err := exporter.ValidateCollectors(metricList, applicationCfg); err != nil {
+		log.Fatal(err)
 	}
 
-	// Register Metrics from each of the endpoints
-	// This invokes the Collect method through the prometheus client libraries.
-	prometheus.MustRegister(&ex)
+	store := config.NewStore(applicationCfg)
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+
+	exporter.SyncPollers(metricList, applicationCfg)
+	go watchReloadSignal(store)
 
-	// Setup HTTP handler
-	http.Handle(applicationCfg.MetricsPath(), promhttp.Handler())
+	// Setup HTTP handlers
+	// Each scrape builds an Exporter for its target, which invokes the Collect method
+	// through the prometheus client libraries.
+	http.Handle(applicationCfg.MetricsPath(), exporter.MetricsHandler(metricList, store))
+	http.HandleFunc("/probe", exporter.ProbeHandler(metricList, store))
+	http.HandleFunc("/-/reload", reloadHandler(store))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 		                <head><title>Sonus Exporter</title></head>
@@ -0,0 +1,306 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"sonus-metrics-exporter/lib"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// customCollectorsFile is the layout of the YAML file defining custom collectors
+type customCollectorsFile struct {
+	Collectors []customCollector `yaml:"collectors"`
+}
+
+// customCollector describes a collector for a Sonus operational endpoint without any Go code
+type customCollector struct {
+	Name              string         `yaml:"name"`
+	Help              string         `yaml:"help"`
+	URL               string         `yaml:"url"`
+	Repeat            string         `yaml:"repeat"`
+	DisabledByDefault bool           `yaml:"disabled_by_default"`
+	RecordPath        string         `yaml:"record_path"`
+	Labels            []customLabel  `yaml:"labels"`
+	Metrics           []customMetric `yaml:"metrics"`
+}
+
+// customLabel takes a label value from a field of each record
+type customLabel struct {
+	Name  string `yaml:"name"`
+	Field string `yaml:"field"`
+}
+
+// customMetric takes a metric value from a field of each record
+type customMetric struct {
+	Name  string             `yaml:"name"`
+	Help  string             `yaml:"help"`
+	Field string             `yaml:"field"`
+	Type  string             `yaml:"type"`
+	Enum  map[string]float64 `yaml:"enum"`
+
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// repeatScopes maps the repeat setting of a custom collector to its repetition, and the labels
+// identifying each repetition
+var repeatScopes = map[string]struct {
+	repetition lib.Repetition
+	labels     []string
+}{
+	"":                   {lib.RepeatNone, nil},
+	"none":               {lib.RepeatNone, nil},
+	"address_context":    {lib.RepeatPerAddressContext, []string{"addresscontext"}},
+	"zone":               {lib.RepeatPerAddressContextZone, []string{"addresscontext", "zone"}},
+	"ip_interface_group": {lib.RepeatPerAddressContextIpInterfaceGroup, []string{"addresscontext", "ipinterfacegroup"}},
+}
+
+// LoadCustomCollectors reads the collectors defined in a YAML file and registers them
+func LoadCustomCollectors(path string) error {
+	var f customCollectorsFile
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&f); err != nil {
+		return fmt.Errorf("unable to parse YAML: %v", err)
+	}
+
+	for i := range f.Collectors {
+		metric, err := f.Collectors[i].sonusMetric()
+		if err != nil {
+			return fmt.Errorf("custom collector %q: %v", f.Collectors[i].Name, err)
+		}
+		lib.Register(metric)
+	}
+
+	return nil
+}
+
+// sonusMetric validates the definition and builds the collector it describes
+func (c *customCollector) sonusMetric() (lib.SonusMetric, error) {
+	scope, ok := repeatScopes[c.Repeat]
+	if !ok {
+		return lib.SonusMetric{}, fmt.Errorf("unknown repeat %q", c.Repeat)
+	}
+	if c.Name == "" || c.URL == "" || c.RecordPath == "" || len(c.Metrics) == 0 {
+		return lib.SonusMetric{}, fmt.Errorf("name, url, record_path and metrics are required")
+	}
+
+	urlTemplate, err := template.New(c.Name).Option("missingkey=error").Parse(c.URL)
+	if err != nil {
+		return lib.SonusMetric{}, fmt.Errorf("invalid url: %v", err)
+	}
+	if err := urlTemplate.Execute(io.Discard, lib.MetricContext{}); err != nil {
+		return lib.SonusMetric{}, fmt.Errorf("invalid url: %v", err)
+	}
+
+	labelNames := append([]string{}, scope.labels...)
+	for _, l := range c.Labels {
+		if l.Name == "" || l.Field == "" {
+			return lib.SonusMetric{}, fmt.Errorf("labels need a name and a field")
+		}
+		labelNames = append(labelNames, l.Name)
+	}
+
+	descs := make(map[string]*prometheus.Desc)
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		if m.Name == "" || m.Field == "" {
+			return lib.SonusMetric{}, fmt.Errorf("metrics need a name and a field")
+		}
+
+		switch m.Type {
+		case "", "gauge":
+			m.valueType = prometheus.GaugeValue
+		case "counter":
+			m.valueType = prometheus.CounterValue
+		default:
+			return lib.SonusMetric{}, fmt.Errorf("metric %q has unknown type %q", m.Name, m.Type)
+		}
+
+		m.desc = prometheus.NewDesc(m.Name, m.Help, labelNames, nil)
+		descs[m.Name] = m.desc
+	}
+
+	return lib.SonusMetric{
+		Name:              c.Name,
+		Help:              c.Help,
+		Processor:         c.process,
+		URLGetter:         func(ctx lib.MetricContext) string { return c.url(urlTemplate, ctx) },
+		APIMetrics:        descs,
+		Repetition:        scope.repetition,
+		DisabledByDefault: c.DisabledByDefault,
+	}, nil
+}
+
+func (c *customCollector) url(urlTemplate *template.Template, ctx lib.MetricContext) string {
+	var b strings.Builder
+
+	if err := urlTemplate.Execute(&b, ctx); err != nil {
+		log.Errorf("Unable to build URL of custom collector %q: %v", c.Name, err)
+	}
+	return b.String()
+}
+
+func (c *customCollector) process(ctx lib.MetricContext, xmlBody *[]byte) {
+	var errors []*error
+
+	root, err := parseXMLTree(*xmlBody)
+	if err != nil {
+		log.Errorf("Failed to deserialize XML of custom collector %q: %v", c.Name, err)
+		errors = append(errors, &err)
+		ctx.ResultChannel <- lib.MetricResult{Name: c.Name, Success: false, Errors: errors}
+		return
+	}
+
+	var scopeValues []string
+	switch c.Repeat {
+	case "address_context":
+		scopeValues = []string{ctx.AddressContext}
+	case "zone":
+		scopeValues = []string{ctx.AddressContext, ctx.Zone}
+	case "ip_interface_group":
+		scopeValues = []string{ctx.AddressContext, ctx.IPInterfaceGroup}
+	}
+
+	// Records with the same label values would make duplicate series, failing the whole scrape
+	var (
+		seen       = make(map[string]bool)
+		duplicates int
+	)
+
+	for _, record := range root.find(c.RecordPath) {
+		labelValues := append([]string{}, scopeValues...)
+		for _, l := range c.Labels {
+			labelValues = append(labelValues, record.text(l.Field))
+		}
+
+		key := strings.Join(labelValues, "\x00")
+		if seen[key] {
+			duplicates++
+			continue
+		}
+		seen[key] = true
+
+		for _, m := range c.Metrics {
+			raw := record.text(m.Field)
+			value, err := m.value(raw)
+			if err != nil {
+				log.Errorf("Custom collector %q could not convert %q of field %q: %v", c.Name, raw, m.Field, err)
+				errors = append(errors, &err)
+				continue
+			}
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(m.desc, m.valueType, value, labelValues...)
+		}
+	}
+
+	if duplicates > 0 {
+		log.Warnf("Custom collector %q skipped %d records with the same label values as an earlier record", c.Name, duplicates)
+	}
+
+	log.Infof("Custom collector %q collected", c.Name)
+	ctx.ResultChannel <- lib.MetricResult{Name: c.Name, Success: len(errors) == 0, Errors: errors}
+}
+
+// value converts the text of a field, using the enum mapping when one is given
+func (m customMetric) value(raw string) (float64, error) {
+	if m.Enum != nil {
+		value, ok := m.Enum[raw]
+		if !ok {
+			return 0, fmt.Errorf("value is not in the enum mapping")
+		}
+		return value, nil
+	}
+
+	switch raw {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+// xmlNode is an element of a parsed XML document, with namespaces removed
+type xmlNode struct {
+	name     string
+	content  string
+	children []*xmlNode
+}
+
+// parseXMLTree parses an XML document into a tree of elements below a nameless document node
+func parseXMLTree(body []byte) (*xmlNode, error) {
+	var (
+		document = &xmlNode{}
+		stack    = []*xmlNode{document}
+		decoder  = xml.NewDecoder(bytes.NewReader(body))
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			stack[len(stack)-1].content += string(t)
+		}
+	}
+
+	return document, nil
+}
+
+// find returns the elements reached by following a slash-separated path of element names
+func (n *xmlNode) find(path string) []*xmlNode {
+	nodes := []*xmlNode{n}
+
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		var next []*xmlNode
+		for _, node := range nodes {
+			for _, child := range node.children {
+				if child.name == name {
+					next = append(next, child)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+// text returns the content of the first element at the path, or an empty string when there is none
+func (n *xmlNode) text(path string) string {
+	nodes := n.find(path)
+	if len(nodes) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(nodes[0].content)
+}
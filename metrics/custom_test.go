@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const customTestXML = `<collection xmlns:y="http://tail-f.com/ns/rest">
+  <zoneStatus xmlns="http://sonusnet.com/ns/mibs/SONUS-ZONE/1.0">
+    <name>ZONE_A</name>
+    <activeSipRegCount> 42 </activeSipRegCount>
+    <usage><inbound>12</inbound></usage>
+  </zoneStatus>
+  <zoneStatus xmlns="http://sonusnet.com/ns/mibs/SONUS-ZONE/1.0">
+    <name>ZONE_B</name>
+    <y:position>2</y:position>
+    <usage><inbound>3</inbound></usage>
+  </zoneStatus>
+</collection>`
+
+func TestXMLTreeFind(t *testing.T) {
+	root, err := parseXMLTree([]byte(customTestXML))
+	if err != nil {
+		t.Fatalf("parseXMLTree() error = %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		names []string
+	}{
+		{"collection/zoneStatus/name", []string{"ZONE_A", "ZONE_B"}},
+		{"/collection/zoneStatus/name/", []string{"ZONE_A", "ZONE_B"}},
+		{"collection/zoneStatus/usage/inbound", []string{"12", "3"}},
+		{"collection/zoneStatus/activeSipRegCount", []string{"42"}},
+		{"collection/missing", nil},
+		{"zoneStatus", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got []string
+			for _, n := range root.find(tt.path) {
+				got = append(got, strings.TrimSpace(n.content))
+			}
+			if !reflect.DeepEqual(got, tt.names) {
+				t.Errorf("find(%q) = %q, want %q", tt.path, got, tt.names)
+			}
+		})
+	}
+}
+
+func TestXMLTreeText(t *testing.T) {
+	root, err := parseXMLTree([]byte(customTestXML))
+	if err != nil {
+		t.Fatalf("parseXMLTree() error = %v", err)
+	}
+	records := root.find("collection/zoneStatus")
+
+	tests := []struct {
+		record int
+		field  string
+		want   string
+	}{
+		{0, "name", "ZONE_A"},
+		{0, "activeSipRegCount", "42"},
+		{0, "usage/inbound", "12"},
+		{1, "position", "2"},
+		{1, "activeSipRegCount", ""},
+		{1, "usage/outbound", ""},
+	}
+
+	for _, tt := range tests {
+		if got := records[tt.record].text(tt.field); got != tt.want {
+			t.Errorf("record %d text(%q) = %q, want %q", tt.record, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseXMLTreeMalformed(t *testing.T) {
+	if _, err := parseXMLTree([]byte("<collection><zoneStatus></collection>")); err == nil {
+		t.Error("parseXMLTree() of malformed XML returned no error")
+	}
+}
+
+func TestCustomMetricValue(t *testing.T) {
+	enum := map[string]float64{"normal": 0, "packetOutageDetected": 1}
+
+	tests := []struct {
+		name    string
+		enum    map[string]float64
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{"number", nil, "12.5", 12.5, false},
+		{"true", nil, "true", 1, false},
+		{"false", nil, "false", 0, false},
+		{"not a number", nil, "inService", 0, true},
+		{"empty", nil, "", 0, true},
+		{"enum hit", enum, "packetOutageDetected", 1, false},
+		{"enum miss", enum, "unknown", 0, true},
+		{"enum does not parse numbers", enum, "1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := customMetric{Enum: tt.enum}.value(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("value(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("value(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
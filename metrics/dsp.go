@@ -16,12 +16,17 @@ const (
 
 var DSPMetric = lib.SonusMetric{
 	Name:       dspName,
+	Help:       "DSP resource and codec utilization",
 	Processor:  processDSPUsage,
 	URLGetter:  getDSPUrl,
 	APIMetrics: dspMetrics,
 	Repetition: lib.RepeatNone,
 }
 
+func init() {
+	lib.Register(DSPMetric)
+}
+
 func getDSPUrl(ctx lib.MetricContext) string {
 	return ctx.APIBase + dspUrlSuffix
 }
@@ -18,12 +18,17 @@ const (
 
 var FanMetric = lib.SonusMetric{
 	Name:       fanName,
+	Help:       "Fan speeds of each server",
 	Processor:  processFans,
 	URLGetter:  getFanUrl,
 	APIMetrics: fanMetrics,
 	Repetition: lib.RepeatNone,
 }
 
+func init() {
+	lib.Register(FanMetric)
+}
+
 func getFanUrl(ctx lib.MetricContext) string {
 	return ctx.APIBase + fanUrlSuffix
 }
@@ -17,12 +17,17 @@ const (
 
 var IPInterfaceMetric = lib.SonusMetric{
 	Name:       ipInterfaceName,
+	Help:       "Packets, bandwidth and media streams of each IP interface",
 	Processor:  processIPInterfaceStatus,
 	URLGetter:  getIPInterfaceGroupUrl,
 	APIMetrics: ipInterfaceMetrics,
 	Repetition: lib.RepeatPerAddressContextIpInterfaceGroup,
 }
 
+func init() {
+	lib.Register(IPInterfaceMetric)
+}
+
 func getIPInterfaceGroupUrl(ctx lib.MetricContext) string {
 	return fmt.Sprintf(ipInterfaceGroupURLFormat, ctx.APIBase, ctx.AddressContext, ctx.IPInterfaceGroup)
 }
@@ -33,6 +38,11 @@ var ipInterfaceMetrics = map[string]*prometheus.Desc{
 		"Current status of ipInterfaceGroup",
 		[]string{"name", "status_text"}, nil,
 	),
+	"IPInterface_Oper_State_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "ipinterface", "oper_state_info"),
+		"Operational state of ipInterface, one series per state. 1 = current state",
+		[]string{"name", "state"}, nil,
+	),
 	"IPInterface_Packets_Received": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "ipinterface", "rxpackets"),
 		"Number of packets received on ipInterfaceGroup",
@@ -81,7 +91,12 @@ func processIPInterfaceStatus(ctx lib.MetricContext, xmlBody *[]byte) {
 	}
 
 	for _, ipInterfaceGroup := range ipInterfaces.IPInterfaces {
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(ipInterfaceMetrics["IPInterface_Oper_Status"], prometheus.GaugeValue, ipInterfaceGroup.operStateToMetric(), ipInterfaceGroup.Name, ipInterfaceGroup.OperState)
+		if ctx.Skip(ipInterfaceName, lib.FilterIPInterface, ipInterfaceGroup.Name) {
+			continue
+		}
+
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(ipInterfaceMetrics["IPInterface_Oper_Status"], prometheus.GaugeValue, lib.BoolToFloat(ipInterfaceGroup.OperState != "resAllocated"), ipInterfaceGroup.Name, ipInterfaceGroup.OperState)
+		ipInterfaceOperStates.Collect(ctx.MetricChannel, ipInterfaceMetrics["IPInterface_Oper_State_Info"], ipInterfaceGroup.OperState, ipInterfaceGroup.Name)
 
 		ctx.MetricChannel <- prometheus.MustNewConstMetric(ipInterfaceMetrics["IPInterface_Packets_Received"], prometheus.CounterValue, ipInterfaceGroup.RxPackets, ipInterfaceGroup.Name)
 		ctx.MetricChannel <- prometheus.MustNewConstMetric(ipInterfaceMetrics["IPInterface_Packets_Transmitted"], prometheus.CounterValue, ipInterfaceGroup.TxPackets, ipInterfaceGroup.Name)
@@ -136,11 +151,4 @@ type ipInterfaceStatus struct {
 	NumMediaStreams   float64 `xml:"http://sonusnet.com/ns/mibs/SONUS-GEN2-IP-INTERFACE/1.0 numMediaStreams"`
 }
 
-func (i ipInterfaceStatus) operStateToMetric() float64 {
-	switch i.OperState {
-	case "resAllocated":
-		return 0
-	default:
-		return 1
-	}
-}
+var ipInterfaceOperStates = lib.NewEnum("ipinterface_oper_state", "resAllocated", "resAllocating", "resDeallocating", "resFailed")
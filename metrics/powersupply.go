@@ -16,12 +16,17 @@ const (
 
 var PowerSupplyMetric = lib.SonusMetric{
 	Name:       powerSupplyName,
+	Help:       "Power and voltage faults of each power supply",
 	Processor:  processPowerSupplies,
 	URLGetter:  getPowerSupplyUrl,
 	APIMetrics: powerSupplyMetrics,
 	Repetition: lib.RepeatNone,
 }
 
+func init() {
+	lib.Register(PowerSupplyMetric)
+}
+
 func getPowerSupplyUrl(ctx lib.MetricContext) string {
 	return ctx.APIBase + powerSupplyUrlSuffix
 }
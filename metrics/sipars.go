@@ -16,12 +16,17 @@ const (
 
 var SipArsMetric = lib.SonusMetric{
 	Name:       sipArsName,
+	Help:       "SIP ARS state of monitored endpoints in each zone",
 	Processor:  processSipArs,
 	URLGetter:  getSipArsUrl,
 	APIMetrics: sipArsMetrics,
 	Repetition: lib.RepeatPerAddressContextZone,
 }
 
+func init() {
+	lib.Register(SipArsMetric)
+}
+
 func getSipArsUrl(ctx lib.MetricContext) string {
 	return fmt.Sprintf(sipArsURLFormat, ctx.APIBase, ctx.AddressContext, ctx.Zone)
 }
@@ -32,6 +37,11 @@ var sipArsMetrics = map[string]*prometheus.Desc{
 		"State of a sipArs monitored endpoint",
 		[]string{"zone", "endpoint_address", "endpoint_port", "state_name"}, nil,
 	),
+	"SIPARS_Endpoint_State_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "sipars", "endpoint_state_info"),
+		"State of a sipArs monitored endpoint, one series per state. 1 = current state",
+		[]string{"zone", "endpoint_address", "endpoint_port", "state"}, nil,
+	),
 }
 
 func processSipArs(ctx lib.MetricContext, xmlBody *[]byte) {
@@ -62,8 +72,12 @@ func processSipArs(ctx lib.MetricContext, xmlBody *[]byte) {
 		} else {
 			endpoint = status.EndpointIpAddress
 		}
+		if ctx.Skip(sipArsName, lib.FilterSipArsEndpoint, endpoint) {
+			continue
+		}
 
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(sipArsMetrics["SIPARS_Endpoint_State"], prometheus.GaugeValue, status.stateToFloat(), ctx.Zone, endpoint, status.EndpointIpPortNum, status.EndpointArsState)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(sipArsMetrics["SIPARS_Endpoint_State"], prometheus.GaugeValue, lib.BoolToFloat(status.EndpointArsState == "blacklisted"), ctx.Zone, endpoint, status.EndpointIpPortNum, status.EndpointArsState)
+		sipArsStates.Collect(ctx.MetricChannel, sipArsMetrics["SIPARS_Endpoint_State_Info"], status.EndpointArsState, ctx.Zone, endpoint, status.EndpointIpPortNum)
 	}
 
 	log.Infof("SIP ARS Metrics for Address Context %q, zone %q collected", ctx.AddressContext, ctx.Zone)
@@ -101,10 +115,4 @@ type sipArsStatus struct {
 	EndpointStateTransition string  `xml:"endpointStateTransition"`
 }
 
-func (s sipArsStatus) stateToFloat() float64 {
-	if s.EndpointArsState == "blacklisted" {
-		return 1
-	} else {
-		return 0
-	}
-}
+var sipArsStates = lib.NewEnum("sipars_endpoint_state", "normal", "blacklisted", "recovering")
@@ -17,12 +17,17 @@ const (
 
 var SipStatisticMetric = lib.SonusMetric{
 	Name:       sipStatisticsName,
+	Help:       "SIP request, response, emergency, HPC, Diameter and S8HR roaming counters of each trunk group",
 	Processor:  processSipStatistics,
 	URLGetter:  getSipStatisticsUrl,
 	APIMetrics: sipStatisticMetrics,
 	Repetition: lib.RepeatPerAddressContextZone,
 }
 
+func init() {
+	lib.Register(SipStatisticMetric)
+}
+
 func getSipStatisticsUrl(ctx lib.MetricContext) string {
 	return fmt.Sprintf(sipStatisticsURLFormat, ctx.APIBase, ctx.AddressContext, ctx.Zone)
 }
@@ -31,22 +36,97 @@ var sipStatisticMetrics = map[string]*prometheus.Desc{
 	"TG_SIP_Req_Sent": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "sip_req_sent"),
 		"Number of SIP requests sent",
-		[]string{"zone", "name", "method"}, nil,
+		[]string{"addresscontext", "zone", "name", "method"}, nil,
 	),
 	"TG_SIP_Req_Received": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "sip_req_recv"),
 		"Number of SIP requests received",
-		[]string{"zone", "name", "method"}, nil,
+		[]string{"addresscontext", "zone", "name", "method"}, nil,
 	),
 	"TG_SIP_Resp_Sent": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "sip_resp_sent"),
 		"Number of SIP responses sent",
-		[]string{"zone", "name", "code"}, nil,
+		[]string{"addresscontext", "zone", "name", "code"}, nil,
 	),
 	"TG_SIP_Resp_Received": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "sip_resp_recv"),
 		"Number of SIP responses received",
-		[]string{"zone", "name", "code"}, nil,
+		[]string{"addresscontext", "zone", "name", "code"}, nil,
+	),
+	"TG_SIP_Retransmissions": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "tg", "sip_retransmissions_total"),
+		"Number of SIP requests retransmitted",
+		[]string{"addresscontext", "zone", "name", "method"}, nil,
+	),
+	"TG_SIP_Retransmission_Ratio": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "tg", "sip_retransmission_ratio"),
+		"SIP requests retransmitted divided by SIP requests sent, since the counters were last reset",
+		[]string{"addresscontext", "zone", "name", "method"}, nil,
+	),
+	"TG_SIP_Parse_Errors": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "sip_parse_errors_total"),
+		"Number of SIP messages received which could not be parsed",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Emergency": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "emergency_total"),
+		"Number of emergency calls, registrations, out of dialog requests and subscriptions, by result",
+		[]string{"addresscontext", "zone", "name", "type", "result"}, nil,
+	),
+	"TG_HPC_Accepted": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "hpc_calls_accepted_total"),
+		"Number of High Priority Calls accepted",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_HPC_Accepted_Direction": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "hpc_calls_accepted_by_direction_total"),
+		"Number of High Priority Calls accepted in each direction",
+		[]string{"addresscontext", "zone", "name", "direction"}, nil,
+	),
+	"TG_HPC_Rejected": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "hpc_calls_rejected_total"),
+		"Number of outbound High Priority Calls rejected with a 403",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_HPC_Overload_Exempt": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "hpc_calls_overload_exempt_total"),
+		"Number of High Priority Calls exempted from overload controls",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Diameter_Calls": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "diameter_rx_calls_total"),
+		"Number of calls which sent Diameter Rx AA requests",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Diameter_Requests_Sent": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "diameter_requests_sent_total"),
+		"Number of Diameter requests sent",
+		[]string{"addresscontext", "zone", "name", "interface", "message"}, nil,
+	),
+	"TG_Diameter_Requests_Received": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "diameter_requests_received_total"),
+		"Number of Diameter requests received",
+		[]string{"addresscontext", "zone", "name", "interface", "message"}, nil,
+	),
+	"TG_Diameter_Request_Errors": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "diameter_request_errors_total"),
+		"Number of Diameter requests sent which timed out or failed",
+		[]string{"addresscontext", "zone", "name", "interface", "message"}, nil,
+	),
+	"TG_Diameter_Answers_Received": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "diameter_answers_received_total"),
+		"Number of Diameter answers received, by result",
+		[]string{"addresscontext", "zone", "name", "interface", "message", "result"}, nil,
+	),
+	"TG_S8HR_Attempts": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "s8hr_attempts_total"),
+		"Number of S8 Home Routed roaming registrations and calls attempted",
+		[]string{"addresscontext", "zone", "name", "direction", "type"}, nil,
+	),
+	"TG_S8HR_Results": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "s8hr_total"),
+		"Number of S8 Home Routed roaming registrations and calls, by result",
+		[]string{"addresscontext", "zone", "name", "direction", "type", "result"}, nil,
 	),
 }
 
@@ -71,28 +151,47 @@ func processSipStatistics(ctx lib.MetricContext, xmlBody *[]byte) {
 	}
 
 	for _, sipStat := range sipStats.SipStatistics {
+		if ctx.Skip(sipStatisticsName, lib.FilterTrunkGroup, sipStat.TrunkGroupName) {
+			continue
+		}
+
 		var sipReqSent = map[string]float64{
-			"INVITE":             sipStat.SndInvite,
-			"PRACK":              sipStat.SndPrack,
-			"INFO":               sipStat.SndInfo,
-			"REFER":              sipStat.SndRefer,
-			"BYE":                sipStat.SndBye,
-			"CANCEL":             sipStat.SndCancel,
-			"REGISTER":           sipStat.SndRegister,
-			"UPDATE":             sipStat.SndUpdate,
-			"SUBSCRIBE":          sipStat.SndSubscriber,
-			"NOTIFY":             sipStat.SndNotify,
-			"OPTIONS":            sipStat.SndOption,
-			"MESSAGE":            sipStat.SndMessage,
-			"PUBLISH":            sipStat.SndPublish,
-			"INVITE (retrans)":   sipStat.InvReTransmit,
-			"REGISTER (retrans)": sipStat.RegReTransmit,
-			"BYE (retrans)":      sipStat.ByeReTransmit,
-			"CANCEL (retrans)":   sipStat.CancelReTransmit,
-			"Other (retrans)":    sipStat.OtherReTransmit,
+			"INVITE":    sipStat.SndInvite,
+			"PRACK":     sipStat.SndPrack,
+			"INFO":      sipStat.SndInfo,
+			"REFER":     sipStat.SndRefer,
+			"BYE":       sipStat.SndBye,
+			"CANCEL":    sipStat.SndCancel,
+			"REGISTER":  sipStat.SndRegister,
+			"UPDATE":    sipStat.SndUpdate,
+			"SUBSCRIBE": sipStat.SndSubscriber,
+			"NOTIFY":    sipStat.SndNotify,
+			"OPTIONS":   sipStat.SndOption,
+			"MESSAGE":   sipStat.SndMessage,
+			"PUBLISH":   sipStat.SndPublish,
+		}
+		var sipRetransmissions = map[string]float64{
+			"INVITE":   sipStat.InvReTransmit,
+			"REGISTER": sipStat.RegReTransmit,
+			"BYE":      sipStat.ByeReTransmit,
+			"CANCEL":   sipStat.CancelReTransmit,
+			"Other":    sipStat.OtherReTransmit,
 		}
 		for n, v := range sipReqSent {
-			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Req_Sent"], prometheus.CounterValue, v, ctx.Zone, sipStat.TrunkGroupName, n)
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Req_Sent"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
+		}
+		for n, v := range sipRetransmissions {
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Retransmissions"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
+
+			// Retransmissions used to be reported as methods of the requests sent
+			if ctx.Compatibility.SIPRetransmissionMethods {
+				ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Req_Sent"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n+" (retrans)")
+			}
+
+			// Other retransmissions cover several methods, so have no count of requests sent to compare with
+			if sent := sipReqSent[n]; sent > 0 {
+				ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Retransmission_Ratio"], prometheus.GaugeValue, v/sent, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
+			}
 		}
 
 		var sipReqReceived = map[string]float64{
@@ -112,7 +211,7 @@ func processSipStatistics(ctx lib.MetricContext, xmlBody *[]byte) {
 			"Unknown":   sipStat.RcvUnknownMsg,
 		}
 		for n, v := range sipReqReceived {
-			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Req_Received"], prometheus.CounterValue, v, ctx.Zone, sipStat.TrunkGroupName, n)
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Req_Received"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
 		}
 
 		var sipRespSent = map[string]float64{
@@ -128,7 +227,7 @@ func processSipStatistics(ctx lib.MetricContext, xmlBody *[]byte) {
 			"Non-INVITE error": sipStat.SndNonInvErr,
 		}
 		for n, v := range sipRespSent {
-			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Resp_Sent"], prometheus.CounterValue, v, ctx.Zone, sipStat.TrunkGroupName, n)
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Resp_Sent"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
 		}
 
 		var sipRespReceived = map[string]float64{
@@ -144,13 +243,90 @@ func processSipStatistics(ctx lib.MetricContext, xmlBody *[]byte) {
 			"Non-INVITE error": sipStat.RcvNonInvErr,
 		}
 		for n, v := range sipRespReceived {
-			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Resp_Received"], prometheus.CounterValue, v, ctx.Zone, sipStat.TrunkGroupName, n)
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_SIP_Resp_Received"], prometheus.CounterValue, v, ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName, n)
 		}
+
+		collectSipServiceStatistics(ctx, sipStat)
+		collectS8HRStatistics(ctx, sipStat)
 	}
 	log.Infof("SIP Statistics Metrics for Address Context %q, zone %q collected", ctx.AddressContext, ctx.Zone)
 	ctx.ResultChannel <- lib.MetricResult{Name: sipStatisticsName, Success: true}
 }
 
+// collectSipServiceStatistics sends the emergency, High Priority Call, Diameter and parse error counters of a trunk group
+func collectSipServiceStatistics(ctx lib.MetricContext, sipStat *sipStatistics) {
+	labels := []string{ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName}
+
+	counter := func(name string, value float64, labelValues ...string) {
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics[name], prometheus.CounterValue, value, append(labels, labelValues...)...)
+	}
+
+	counter("TG_SIP_Parse_Errors", sipStat.ParseError)
+
+	// Keyed by type and result
+	var emergency = map[[2]string]float64{
+		{"call", "accepted"}:                  sipStat.EmergencyAccept,
+		{"call", "rejected_bandwidth"}:        sipStat.EmergencyRejectBWCall,
+		{"call", "rejected_policer"}:          sipStat.EmergencyRejectPolicer,
+		{"registration", "accepted"}:          sipStat.EmergencyRegAccept,
+		{"registration", "rejected_limit"}:    sipStat.EmergencyRegRejectLimit,
+		{"registration", "rejected_policer"}:  sipStat.EmergencyRegRejectPolicer,
+		{"out_of_dialog", "accepted"}:         sipStat.EmergencyOODAccept,
+		{"out_of_dialog", "rejected_policer"}: sipStat.EmergencyOODRejectPolicer,
+		{"subscription", "accepted"}:          sipStat.EmergencySubsAccept,
+		{"subscription", "rejected_limit"}:    sipStat.EmergencySubsRejectLimit,
+		{"subscription", "rejected_policer"}:  sipStat.EmergencySubsRejectPolicer,
+	}
+	for k, v := range emergency {
+		counter("TG_Emergency", v, k[0], k[1])
+	}
+
+	counter("TG_HPC_Accepted", sipStat.HpcAccept)
+	counter("TG_HPC_Accepted_Direction", sipStat.InHpcAccept, "inbound")
+	counter("TG_HPC_Accepted_Direction", sipStat.OutHpcAccept, "outbound")
+	counter("TG_HPC_Rejected", sipStat.Hpc403Out)
+	counter("TG_HPC_Overload_Exempt", sipStat.HpcOverloadExempt)
+
+	// Rx is the policy interface towards the PCRF, Sh the subscriber data interface towards the HSS
+	counter("TG_Diameter_Calls", sipStat.NumberOfCallsSendingAARs)
+	counter("TG_Diameter_Requests_Sent", sipStat.NumberOfTotalAARSent, "rx", "AAR")
+	counter("TG_Diameter_Requests_Sent", sipStat.NumberOfSentSTRs, "rx", "STR")
+	counter("TG_Diameter_Requests_Sent", sipStat.NumberOfTotalUDRSent, "sh", "UDR")
+	counter("TG_Diameter_Requests_Received", sipStat.NumberOfReceivedRARs, "rx", "RAR")
+	counter("TG_Diameter_Requests_Received", sipStat.NumberOfReceivedASRs, "rx", "ASR")
+	counter("TG_Diameter_Request_Errors", sipStat.NumberOfTimeoutOrErrorAAR, "rx", "AAR")
+	counter("TG_Diameter_Request_Errors", sipStat.NumberOfTimeoutOrErrorUDR, "sh", "UDR")
+	counter("TG_Diameter_Answers_Received", sipStat.NumberOfReceivedAAASuccesses, "rx", "AAA", "success")
+	counter("TG_Diameter_Answers_Received", sipStat.NumberOfReceivedAAAFailures, "rx", "AAA", "failure")
+	counter("TG_Diameter_Answers_Received", sipStat.NumberOfReceivedUDASuccesses, "sh", "UDA", "success")
+	counter("TG_Diameter_Answers_Received", sipStat.NumberOfReceivedUDAFailures, "sh", "UDA", "failure")
+}
+
+// collectS8HRStatistics sends the S8 Home Routed VoLTE roaming counters of a trunk group
+func collectS8HRStatistics(ctx lib.MetricContext, sipStat *sipStatistics) {
+	labels := []string{ctx.AddressContext, ctx.Zone, sipStat.TrunkGroupName}
+
+	// The SBC only counts attempts for outbound registrations and calls
+	ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_S8HR_Attempts"], prometheus.CounterValue, sipStat.TotNumOfS8hrOutbndReg, append(labels, "outbound", "registration")...)
+	ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_S8HR_Attempts"], prometheus.CounterValue, sipStat.TotNumOfS8hrOutbndNormalCall, append(labels, "outbound", "call")...)
+
+	// Keyed by direction, type and result
+	var s8hr = map[[3]string]float64{
+		{"outbound", "registration", "success"}:   sipStat.NumOfS8hrOutbndRegSuc,
+		{"outbound", "registration", "failure"}:   sipStat.NumOfS8hrOutbndRegFail,
+		{"outbound", "call", "success"}:           sipStat.NumOfS8hrOutbndNormalCallSuc,
+		{"outbound", "call", "failure"}:           sipStat.NumOfS8hrOutbndNormalCallFail,
+		{"outbound", "emergency_call", "failure"}: sipStat.NumOfS8hrOutbndEmgCallRej,
+		{"inbound", "registration", "success"}:    sipStat.NumOfS8hrInboundRegSuc,
+		{"inbound", "registration", "failure"}:    sipStat.NumOfS8hrInboundRegFail,
+		{"inbound", "emergency_call", "success"}:  sipStat.NumOfS8hrInboundEmgCallSuc,
+		{"inbound", "emergency_call", "failure"}:  sipStat.NumOfS8hrInboundEmgCallFail,
+	}
+	for k, v := range s8hr {
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(sipStatisticMetrics["TG_S8HR_Results"], prometheus.CounterValue, v, append(labels, k[0], k[1], k[2])...)
+	}
+}
+
 /*
 <collection xmlns:y="http://tail-f.com/ns/rest">
   <sipCurrentStatistics xmlns="http://sonusnet.com/ns/mibs/SONUS-SIP-PEER-PERF-STATS/1.0">
@@ -12,16 +12,22 @@ import (
 const (
 	trunkGroupName      = "TrunkGroup"
 	trunkGroupUrlSuffix = "/operational/global/globalTrunkGroupStatus/"
+	unlimitedBandwidth  = -1
 )
 
 var TGMetric = lib.SonusMetric{
 	Name:       trunkGroupName,
+	Help:       "State, calls and bandwidth of each trunk group",
 	Processor:  processTGs,
 	URLGetter:  getTGUrl,
 	APIMetrics: tgMetrics,
 	Repetition: lib.RepeatNone,
 }
 
+func init() {
+	lib.Register(TGMetric)
+}
+
 func getTGUrl(ctx lib.MetricContext) string {
 	return ctx.APIBase + trunkGroupUrlSuffix
 }
@@ -30,27 +36,72 @@ var tgMetrics = map[string]*prometheus.Desc{
 	"TG_Bandwidth": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "bytes"),
 		"Bandwidth in use by current calls",
-		[]string{"zone", "name", "direction"}, nil,
+		[]string{"addresscontext", "zone", "name", "direction"}, nil,
 	),
 	"TG_OBState": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "outbound_state"),
 		"State of outbound calls on the trunkgroup",
-		[]string{"zone", "name"}, nil,
+		[]string{"addresscontext", "zone", "name"}, nil,
 	),
 	"TG_State": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "state"),
 		"State of the trunkgroup",
-		[]string{"zone", "name"}, nil,
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_State_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "state_info"),
+		"State of the trunkgroup, one series per state. 1 = current state",
+		[]string{"addresscontext", "zone", "name", "state"}, nil,
+	),
+	"TG_OBState_Info": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "outbound_state_info"),
+		"Packet outage detection state of the trunkgroup, one series per state. 1 = current state",
+		[]string{"addresscontext", "zone", "name", "state"}, nil,
 	),
 	"TG_TotalChans": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "total_channels"),
 		"Number of configured channels",
-		[]string{"zone", "name"}, nil,
+		[]string{"addresscontext", "zone", "name"}, nil,
 	),
 	"TG_Usage": prometheus.NewDesc(
 		prometheus.BuildFQName("sonus", "TG", "usage_total"),
 		"Number of active calls",
-		[]string{"zone", "name", "direction"}, nil,
+		[]string{"addresscontext", "zone", "name", "direction"}, nil,
+	),
+	"TG_Calls_Available": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "calls_available"),
+		"Number of calls which can still be placed",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Calls_Reserved": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "calls_reserved"),
+		"Number of channels reserved for calls in each direction",
+		[]string{"addresscontext", "zone", "name", "direction"}, nil,
+	),
+	"TG_Priority_Usage": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "priority_usage_total"),
+		"Number of active priority calls",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Priority_Bandwidth": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "priority_bytes"),
+		"Bandwidth in use by current priority calls",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Bandwidth_Limit": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "bandwidth_limit_bytes"),
+		"Current bandwidth limit, not exported when the bandwidth is unlimited",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Bandwidth_Available": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "bandwidth_available_bytes"),
+		"Bandwidth available to new calls, not exported when the bandwidth is unlimited",
+		[]string{"addresscontext", "zone", "name"}, nil,
+	),
+	"TG_Bandwidth_Unlimited": prometheus.NewDesc(
+		prometheus.BuildFQName("sonus", "TG", "bandwidth_unlimited"),
+		"Whether the bandwidth of the trunkgroup is unlimited. 1 = unlimited",
+		[]string{"addresscontext", "zone", "name"}, nil,
 	),
 }
 
@@ -70,13 +121,35 @@ func processTGs(ctx lib.MetricContext, xmlBody *[]byte) {
 	}
 
 	for _, tg := range tgs.TrunkGroupStatus {
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Usage"], prometheus.GaugeValue, tg.InboundCallsUsage, tg.Zone, tg.Name, "inbound")
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Usage"], prometheus.GaugeValue, tg.OutboundCallsUsage, tg.Zone, tg.Name, "outbound")
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth"], prometheus.GaugeValue, tg.BandwidthInboundUsage, tg.Zone, tg.Name, "inbound")
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth"], prometheus.GaugeValue, tg.BandwidthOutboundUsage, tg.Zone, tg.Name, "outbound")
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_TotalChans"], prometheus.GaugeValue, tg.TotalCallsConfigured, tg.Zone, tg.Name)
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_State"], prometheus.GaugeValue, trunkGroupStatus.stateToMetric(*tg), tg.Zone, tg.Name)
-		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_OBState"], prometheus.GaugeValue, trunkGroupStatus.outStateToMetric(*tg), tg.Zone, tg.Name)
+		if ctx.Skip(trunkGroupName, lib.FilterZone, tg.Zone) || ctx.Skip(trunkGroupName, lib.FilterTrunkGroup, tg.Name) {
+			continue
+		}
+
+		labels := []string{tg.AddressContext, tg.Zone, tg.Name}
+
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Usage"], prometheus.GaugeValue, tg.InboundCallsUsage, append(labels, "inbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Usage"], prometheus.GaugeValue, tg.OutboundCallsUsage, append(labels, "outbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth"], prometheus.GaugeValue, tg.BandwidthInboundUsage, append(labels, "inbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth"], prometheus.GaugeValue, tg.BandwidthOutboundUsage, append(labels, "outbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_TotalChans"], prometheus.GaugeValue, tg.TotalCallsConfigured, labels...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Calls_Available"], prometheus.GaugeValue, tg.TotalCallsAvailable, labels...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Calls_Reserved"], prometheus.GaugeValue, tg.TotalCallsInboundReserved, append(labels, "inbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Calls_Reserved"], prometheus.GaugeValue, tg.TotalOutboundCallsReserved, append(labels, "outbound")...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Priority_Usage"], prometheus.GaugeValue, tg.PriorityCallUsage, labels...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Priority_Bandwidth"], prometheus.GaugeValue, tg.PriorityBwUsage, labels...)
+
+		// The SBC reports a bandwidth limit and availability of -1 when the trunkgroup has no bandwidth limit
+		unlimited := tg.BandwidthCurrentLimit == unlimitedBandwidth
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth_Unlimited"], prometheus.GaugeValue, lib.BoolToFloat(unlimited), labels...)
+		if !unlimited {
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth_Limit"], prometheus.GaugeValue, tg.BandwidthCurrentLimit, labels...)
+			ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_Bandwidth_Available"], prometheus.GaugeValue, tg.BandwidthAvailable, labels...)
+		}
+
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_State"], prometheus.GaugeValue, lib.BoolToFloat(tg.State == "inService"), labels...)
+		ctx.MetricChannel <- prometheus.MustNewConstMetric(tgMetrics["TG_OBState"], prometheus.GaugeValue, lib.BoolToFloat(tg.PacketOutDetectState == "normal"), labels...)
+		tgStates.Collect(ctx.MetricChannel, tgMetrics["TG_State_Info"], tg.State, labels...)
+		tgOutStates.Collect(ctx.MetricChannel, tgMetrics["TG_OBState_Info"], tg.PacketOutDetectState, labels...)
 	}
 
 	log.Info("Trunk Group Metrics collected")
@@ -132,20 +205,7 @@ type trunkGroupStatus struct {
 	Zone                       string  `xml:"http://sonusnet.com/ns/mibs/SONUS-GLOBAL-TRUNKGROUP/1.0 zone"`
 }
 
-func (t trunkGroupStatus) stateToMetric() float64 {
-	switch t.State {
-	case "inService":
-		return 1
-	default:
-		return 0
-	}
-}
-
-func (t trunkGroupStatus) outStateToMetric() float64 {
-	switch t.PacketOutDetectState {
-	case "normal":
-		return 1
-	default:
-		return 0
-	}
-}
+var (
+	tgStates    = lib.NewEnum("trunkgroup_state", "inService", "outOfService")
+	tgOutStates = lib.NewEnum("trunkgroup_outbound_state", "normal", "packetOutageDetected")
+)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"sonus-metrics-exporter/config"
+	"sonus-metrics-exporter/exporter"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// reloadMutex ensures only one reload runs at a time
+	reloadMutex sync.Mutex
+
+	configLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful",
+	})
+
+	configLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "sonus",
+		Subsystem: "exporter",
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(configLastReloadSuccessful)
+	prometheus.MustRegister(configLastReloadSuccessTimestamp)
+}
+
+// reloadConfig re-reads and validates the configuration, swapping it into the store if it is valid.
+// The previous configuration is kept when the new one fails to load.
+func reloadConfig(store *config.Store) error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	c, err := store.Get().Reload()
+	if err == nil {
+		err = exporter.ValidateCollectors(metricList, c)
+	}
+
+	if err != nil {
+		log.Errorf("Error reloading configuration: %v", err)
+		configLastReloadSuccessful.Set(0)
+		return err
+	}
+
+	store.Set(c)
+	exporter.SyncPollers(metricList, c)
+	configLastReloadSuccessful.Set(1)
+	configLastReloadSuccessTimestamp.SetToCurrentTime()
+
+	log.Infof("Configuration reloaded, %d target(s) configured", len(c.Targets))
+	return nil
+}
+
+// watchReloadSignal reloads the configuration each time the process receives SIGHUP
+func watchReloadSignal(store *config.Store) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		log.Info("Received SIGHUP, reloading configuration")
+		reloadConfig(store)
+	}
+}
+
+// reloadHandler reloads the configuration on POST requests
+func reloadHandler(store *config.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "This endpoint requires a POST request", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := reloadConfig(store); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reload config: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+}